@@ -10,6 +10,6 @@ import (
 func main() {
 	if err := dbquery.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		os.Exit(dbquery.ExitCode(err))
 	}
 }
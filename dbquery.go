@@ -0,0 +1,37 @@
+// Package dbquery exposes the natural-language-to-SQL pipeline behind the
+// dbquery CLI for use as a library.
+package dbquery
+
+import (
+	"context"
+
+	core "dbquery/internal/dbquery"
+)
+
+type (
+	Config    = core.Config
+	Client    = core.Client
+	Error     = core.Error
+	LLMClient = core.LLMClient
+)
+
+var (
+	ErrConfig            = core.ErrConfig
+	ErrMissingAPIKey     = core.ErrMissingAPIKey
+	ErrDBConnect         = core.ErrDBConnect
+	ErrLLM               = core.ErrLLM
+	ErrEmptySQL          = core.ErrEmptySQL
+	ErrReadOnlyViolation = core.ErrReadOnlyViolation
+	ErrPlanCostExceeded  = core.ErrPlanCostExceeded
+	ErrUnknownTable      = core.ErrUnknownTable
+	ErrUnboundedScan     = core.ErrUnboundedScan
+	ErrQuery             = core.ErrQuery
+)
+
+func DefaultConfig() Config {
+	return core.DefaultConfig()
+}
+
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	return core.NewClient(ctx, cfg)
+}
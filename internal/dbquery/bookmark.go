@@ -0,0 +1,130 @@
+package dbquery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	bookmarkActionList   = "list"
+	bookmarkActionDelete = "delete"
+)
+
+type Bookmark struct {
+	Query   string    `json:"query"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+func loadBookmarks(path string) (map[string]Bookmark, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]Bookmark{}, nil
+		}
+		return nil, fmt.Errorf("read bookmarks file: %w", err)
+	}
+
+	bookmarks := make(map[string]Bookmark)
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return bookmarks, nil
+	}
+
+	if err := json.Unmarshal(raw, &bookmarks); err != nil {
+		return nil, fmt.Errorf("parse bookmarks file: %w", err)
+	}
+
+	return bookmarks, nil
+}
+
+func writeBookmarks(path string, bookmarks map[string]Bookmark) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create bookmarks directory: %w", err)
+	}
+
+	payload, err := json.MarshalIndent(bookmarks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode bookmarks: %w", err)
+	}
+	if err := os.WriteFile(path, payload, 0o644); err != nil {
+		return fmt.Errorf("write bookmarks file: %w", err)
+	}
+
+	return nil
+}
+
+func findBookmark(path, name string) (Bookmark, error) {
+	bookmarks, err := loadBookmarks(path)
+	if err != nil {
+		return Bookmark{}, err
+	}
+	b, ok := bookmarks[name]
+	if !ok {
+		return Bookmark{}, fmt.Errorf("bookmark %q not found in %s (see `dbquery bookmarks list`)", name, path)
+	}
+	return b, nil
+}
+
+func runSaveBookmark(cfg Config) error {
+	bookmarks, err := loadBookmarks(cfg.BookmarksFile)
+	if err != nil {
+		return err
+	}
+
+	if existing, ok := bookmarks[cfg.BookmarkName]; ok && !cfg.BookmarkOverwrite && existing.Query != cfg.NLQuery {
+		return fmt.Errorf("bookmark %q already exists (use --overwrite to replace it)", cfg.BookmarkName)
+	}
+
+	bookmarks[cfg.BookmarkName] = Bookmark{Query: cfg.NLQuery, SavedAt: time.Now().UTC()}
+	if err := writeBookmarks(cfg.BookmarksFile, bookmarks); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Saved bookmark %q. Run it with `dbquery run %s`.\n", cfg.BookmarkName, cfg.BookmarkName)
+	return nil
+}
+
+func runBookmarks(cfg Config) error {
+	bookmarks, err := loadBookmarks(cfg.BookmarksFile)
+	if err != nil {
+		return err
+	}
+
+	switch cfg.BookmarkAction {
+	case bookmarkActionDelete:
+		if _, ok := bookmarks[cfg.BookmarkName]; !ok {
+			return fmt.Errorf("bookmark %q not found in %s", cfg.BookmarkName, cfg.BookmarksFile)
+		}
+		delete(bookmarks, cfg.BookmarkName)
+		if err := writeBookmarks(cfg.BookmarksFile, bookmarks); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Deleted bookmark %q.\n", cfg.BookmarkName)
+		return nil
+	case bookmarkActionList:
+	default:
+		return fmt.Errorf("unsupported bookmarks action %q (expected list|delete)", cfg.BookmarkAction)
+	}
+
+	if len(bookmarks) == 0 {
+		fmt.Println("No bookmarks saved.")
+		return nil
+	}
+
+	names := make([]string, 0, len(bookmarks))
+	for name := range bookmarks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, map[string]any{"name": name, "query": bookmarks[name].Query})
+	}
+	fmt.Println(renderTable([]string{"name", "query"}, rows, renderOptions{}))
+	return nil
+}
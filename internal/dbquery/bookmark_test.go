@@ -0,0 +1,65 @@
+package dbquery
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveAndRunBookmark(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+
+	cfg, err := parseConfig([]string{"save", "signups", "daily signups for the last 30 days", "--bookmarks-file", path})
+	if err != nil {
+		t.Fatalf("parse save returned error: %v", err)
+	}
+	if cfg.Mode != modeSave || cfg.BookmarkName != "signups" || cfg.NLQuery != "daily signups for the last 30 days" {
+		t.Fatalf("unexpected save config: %+v", cfg)
+	}
+	if err := runSaveBookmark(cfg); err != nil {
+		t.Fatalf("runSaveBookmark returned error: %v", err)
+	}
+
+	cfg.NLQuery = "weekly signups"
+	if err := runSaveBookmark(cfg); err == nil || !strings.Contains(err.Error(), "--overwrite") {
+		t.Fatalf("expected overwrite error, got %v", err)
+	}
+	cfg.BookmarkOverwrite = true
+	if err := runSaveBookmark(cfg); err != nil {
+		t.Fatalf("runSaveBookmark with overwrite returned error: %v", err)
+	}
+
+	runCfg, err := parseQueryConfig(modeRun, testQueryArgs(t, "signups", "--bookmarks-file", path, "--db-url", "./app.db", "--llm-provider", "mock"))
+	if err != nil {
+		t.Fatalf("parse run returned error: %v", err)
+	}
+	if runCfg.NLQuery != "weekly signups" {
+		t.Fatalf("expected bookmark query, got %q", runCfg.NLQuery)
+	}
+
+	if _, err := parseQueryConfig(modeRun, testQueryArgs(t, "missing", "--bookmarks-file", path, "--db-url", "./app.db")); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing bookmark error, got %v", err)
+	}
+	if _, err := parseQueryConfig(modeRun, testQueryArgs(t, "signups", "--bookmarks-file", path, "--db-url", "./app.db", "--query", "other")); err == nil {
+		t.Fatal("expected error when combining run with --query")
+	}
+}
+
+func TestParseBookmarksConfig(t *testing.T) {
+	cfg, err := parseBookmarksConfig(nil)
+	if err != nil || cfg.BookmarkAction != bookmarkActionList {
+		t.Fatalf("expected default list action, got %q (%v)", cfg.BookmarkAction, err)
+	}
+
+	cfg, err = parseBookmarksConfig([]string{"delete", "signups"})
+	if err != nil || cfg.BookmarkAction != bookmarkActionDelete || cfg.BookmarkName != "signups" {
+		t.Fatalf("unexpected delete config: %+v (%v)", cfg, err)
+	}
+
+	if _, err := parseBookmarksConfig([]string{"delete"}); err == nil {
+		t.Fatal("expected error for delete without a name")
+	}
+	if _, err := parseSaveConfig([]string{"only-name"}); err == nil {
+		t.Fatal("expected error for save without a query")
+	}
+}
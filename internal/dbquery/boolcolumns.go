@@ -0,0 +1,48 @@
+package dbquery
+
+import (
+	"strconv"
+	"strings"
+)
+
+func boolColumns(columns []string, rows []map[string]any, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	var flagged []string
+	for _, col := range columns {
+		for _, name := range names {
+			if strings.EqualFold(col, name) {
+				flagged = append(flagged, col)
+				break
+			}
+		}
+	}
+
+	for _, row := range rows {
+		for _, col := range flagged {
+			if b, ok := boolColumnValue(row[col]); ok {
+				row[col] = b
+			}
+		}
+	}
+}
+
+func boolColumnValue(v any) (bool, bool) {
+	switch t := normalizeDBValue(v).(type) {
+	case bool:
+		return t, true
+	case int64:
+		return t != 0, t == 0 || t == 1
+	case int:
+		return t != 0, t == 0 || t == 1
+	case float64:
+		return t != 0, t == 0 || t == 1
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(t))
+		return b, err == nil
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,45 @@
+package dbquery
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBoolColumns(t *testing.T) {
+	columns := []string{"id", "Active", "verified", "note"}
+	rows := []map[string]any{
+		{"id": int64(1), "Active": int64(1), "verified": "f", "note": "ok"},
+		{"id": int64(2), "Active": int64(0), "verified": nil, "note": "ok"},
+		{"id": int64(3), "Active": int64(7), "verified": "yes", "note": "ok"},
+	}
+
+	boolColumns(columns, rows, []string{"active", "verified", "missing"})
+
+	if rows[0]["Active"] != true || rows[0]["verified"] != false || rows[1]["Active"] != false {
+		t.Fatalf("expected 0/1 and t/f values as booleans: %+v", rows[:2])
+	}
+	if rows[1]["verified"] != nil {
+		t.Fatalf("NULL should stay NULL, got %v", rows[1]["verified"])
+	}
+	if rows[2]["Active"] != int64(7) || rows[2]["verified"] != "yes" {
+		t.Fatalf("values that are not booleans should be left alone: %+v", rows[2])
+	}
+	if rows[0]["id"] != int64(1) || rows[0]["note"] != "ok" {
+		t.Fatalf("other columns changed: %+v", rows[0])
+	}
+}
+
+func TestExecuteQuerySQLiteBooleanStaysNumeric(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE flags (id INTEGER PRIMARY KEY, enabled BOOLEAN)`,
+		`INSERT INTO flags (enabled) VALUES (1), (0)`,
+	)
+
+	_, rows, err := executeQuery(context.Background(), db, "SELECT enabled FROM flags ORDER BY id", timeFormats{})
+	if err != nil {
+		t.Fatalf("executeQuery returned error: %v", err)
+	}
+	if rows[0]["enabled"] != int64(1) || rows[1]["enabled"] != int64(0) {
+		t.Fatalf("SQLite has no real boolean type; without --bool-columns values stay 0/1, got %+v", rows)
+	}
+}
@@ -0,0 +1,42 @@
+package dbquery
+
+type chatTurn struct {
+	Query string
+	SQL   string
+}
+
+func appendChatTurn(turns []chatTurn, turn chatTurn, max int) []chatTurn {
+	if max <= 0 || turn.SQL == "" {
+		return turns
+	}
+	turns = append(turns, turn)
+	if len(turns) > max {
+		turns = append([]chatTurn(nil), turns[len(turns)-max:]...)
+	}
+	return turns
+}
+
+func limitChatTurns(turns []chatTurn, maxTokens int) []chatTurn {
+	if maxTokens <= 0 {
+		return turns
+	}
+	used := 0
+	for i := len(turns) - 1; i >= 0; i-- {
+		used += estimateTokens(turns[i].Query) + estimateTokens(turns[i].SQL)
+		if used > maxTokens {
+			return turns[i+1:]
+		}
+	}
+	return turns
+}
+
+func chatTurnMessages(turns []chatTurn) []chatMessage {
+	messages := make([]chatMessage, 0, 2*len(turns))
+	for _, t := range turns {
+		messages = append(messages,
+			chatMessage{Role: "user", Content: "User request:\n" + t.Query},
+			chatMessage{Role: "assistant", Content: t.SQL},
+		)
+	}
+	return messages
+}
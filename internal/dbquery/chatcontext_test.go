@@ -0,0 +1,91 @@
+package dbquery
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendChatTurn(t *testing.T) {
+	var turns []chatTurn
+	for i, q := range []string{"a", "b", "c"} {
+		turns = appendChatTurn(turns, chatTurn{Query: q, SQL: "SELECT " + string(rune('1'+i))}, 2)
+	}
+	turns = appendChatTurn(turns, chatTurn{Query: "failed"}, 2)
+	if len(turns) != 2 || turns[0].Query != "b" || turns[1].Query != "c" {
+		t.Fatalf("expected the last two turns with SQL, got %+v", turns)
+	}
+
+	if got := appendChatTurn(nil, chatTurn{Query: "a", SQL: "SELECT 1"}, 0); got != nil {
+		t.Fatalf("expected no context when turns are disabled, got %+v", got)
+	}
+}
+
+func TestLimitChatTurnsByTokens(t *testing.T) {
+	turns := []chatTurn{
+		{Query: strings.Repeat("a", 400), SQL: "SELECT 1"},
+		{Query: "b", SQL: "SELECT 2"},
+		{Query: "c", SQL: "SELECT 3"},
+	}
+	got := limitChatTurns(turns, 50)
+	if len(got) != 2 || got[0].Query != "b" {
+		t.Fatalf("expected the oldest, oversized turn to be dropped, got %+v", got)
+	}
+	if got := limitChatTurns(turns, 0); len(got) != 3 {
+		t.Fatalf("expected no cap with a zero budget, got %d turns", len(got))
+	}
+}
+
+type turnRecordingLLMClient struct {
+	replies []string
+	turns   [][]chatTurn
+}
+
+func (c *turnRecordingLLMClient) GenerateSQL(ctx context.Context, schemaContext, naturalQuery string) (string, error) {
+	return c.generateSQLWithTurns(ctx, schemaContext, naturalQuery, nil)
+}
+
+func (c *turnRecordingLLMClient) generateSQLWithTurns(_ context.Context, _, _ string, turns []chatTurn) (string, error) {
+	c.turns = append(c.turns, turns)
+	if len(c.replies) == 0 {
+		return "SELECT 1", nil
+	}
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return reply, nil
+}
+
+func TestChatTurnsReachLLMPrompt(t *testing.T) {
+	turns := []chatTurn{{Query: "list users", SQL: "SELECT *\n  FROM users"}}
+
+	messages := buildLLMMessages(Config{Limit: 10, ContextMaxTokens: 1000}, "- users (id INTEGER)", "now only the active ones", turns)
+	roles := make([]string, len(messages))
+	for i, m := range messages {
+		roles[i] = m.Role
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,user" {
+		t.Fatalf("unexpected message roles: %v", roles)
+	}
+	if messages[1].Content != "User request:\nlist users" || messages[2].Content != "SELECT *\n  FROM users" {
+		t.Fatalf("unexpected earlier turn messages: %+v", messages[1:3])
+	}
+	if !strings.Contains(messages[3].Content, "now only the active ones") || !strings.Contains(messages[3].Content, "- users (id INTEGER)") {
+		t.Fatalf("expected the new request and schema in the last message: %q", messages[3].Content)
+	}
+
+	dump := formatPrompts(messages)
+	if !strings.Contains(dump, "Earlier user request:\nUser request:\nlist users\n\nEarlier reply:\nSELECT *") {
+		t.Fatalf("unexpected prompt dump:\n%s", dump)
+	}
+
+	llm := &turnRecordingLLMClient{}
+	cfg := Config{Mode: modeChat, DBType: "sqlite", Output: "json", Timeout: 5 * time.Second, NoHistory: true, LLMClient: llm}
+	db := openTestSQLite(t)
+	if _, err := processNaturalLanguageQuery(context.Background(), db, cfg, "", "now only the active ones", turns); err != nil {
+		t.Fatalf("processNaturalLanguageQuery returned error: %v", err)
+	}
+	if len(llm.turns) != 1 || len(llm.turns[0]) != 1 || llm.turns[0][0].Query != "list users" {
+		t.Fatalf("expected the LLM client to see the earlier turn, got %+v", llm.turns)
+	}
+}
@@ -0,0 +1,100 @@
+package dbquery
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	replProtocolText = "text"
+	replProtocolJSON = "json"
+)
+
+type replRequest struct {
+	ID    any    `json:"id,omitempty"`
+	Query string `json:"query"`
+}
+
+type replResponse struct {
+	ID       any              `json:"id,omitempty"`
+	SQL      string           `json:"sql,omitempty"`
+	Columns  []string         `json:"columns"`
+	Rows     []map[string]any `json:"rows"`
+	RowCount int              `json:"row_count"`
+	Error    string           `json:"error,omitempty"`
+}
+
+func serveJSONREPL(cfg Config, db *sql.DB, conn *sql.Conn, schemaContext string, in io.Reader, out io.Writer) (*sql.Conn, error) {
+	// Results are returned in the response objects, so nothing else may reach stdout.
+	cfg.stdout = io.Discard
+	cfg.NoProgress = true
+	cfg.Quiet = true
+
+	enc := json.NewEncoder(out)
+	var turns []chatTurn
+	respond := func(id any, query string) error {
+		entry, err := processNaturalLanguageQuery(context.Background(), conn, cfg, schemaContext, query, turns)
+		if err == nil {
+			turns = appendChatTurn(turns, chatTurn{Query: query, SQL: entry.SQL}, cfg.ContextTurns)
+		}
+
+		resp := replResponse{ID: id, SQL: entry.SQL, Columns: []string{}, Rows: []map[string]any{}}
+		if entry.result != nil {
+			if entry.result.Columns != nil {
+				resp.Columns = entry.result.Columns
+			}
+			if entry.result.Rows != nil {
+				resp.Rows = entry.result.Rows
+			}
+			resp.RowCount = len(entry.result.Rows)
+		}
+		if err != nil {
+			resp.Error = err.Error()
+			if isConnectionLost(err) {
+				if session, rerr := reconnectSession(cfg, db, conn); rerr == nil {
+					conn = session
+				} else {
+					resp.Error += "; reconnect: " + rerr.Error()
+				}
+			}
+		}
+		return enc.Encode(resp)
+	}
+
+	if q := strings.TrimSpace(cfg.NLQuery); q != "" {
+		if err := respond(nil, q); err != nil {
+			return conn, fmt.Errorf("write response: %w", err)
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req replRequest
+		err := json.Unmarshal([]byte(line), &req)
+		switch {
+		case err != nil:
+			err = enc.Encode(replResponse{Columns: []string{}, Rows: []map[string]any{}, Error: "invalid request: " + err.Error()})
+		case strings.TrimSpace(req.Query) == "":
+			err = enc.Encode(replResponse{ID: req.ID, Columns: []string{}, Rows: []map[string]any{}, Error: `request needs a non-empty "query"`})
+		default:
+			err = respond(req.ID, strings.TrimSpace(req.Query))
+		}
+		if err != nil {
+			return conn, fmt.Errorf("write response: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return conn, fmt.Errorf("read requests: %w", err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,81 @@
+package dbquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeJSONREPL(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`, `INSERT INTO users (email) VALUES ('a@example.com')`)
+	conn, err := openSession(context.Background(), db, nil)
+	if err != nil {
+		t.Fatalf("openSession returned error: %v", err)
+	}
+	defer conn.Close()
+
+	llm := &scriptedLLMClient{replies: []string{"SELECT id, email FROM users", "SELECT * FROM missing"}}
+	cfg := Config{Mode: modeChat, DBType: "sqlite", Output: "table", Limit: 10, Timeout: 5 * time.Second, NoHistory: true, ContextTurns: 5, LLMClient: llm}
+	in := strings.NewReader(`{"id": 1, "query": "list users"}` + "\n\n" + `not json` + "\n" + `{"id": "b", "query": "broken"}` + "\n" + `{"id": 3}` + "\n")
+	var out bytes.Buffer
+
+	if _, err := serveJSONREPL(cfg, db, conn, "", in, &out); err != nil {
+		t.Fatalf("serveJSONREPL returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected one response per request, got %d:\n%s", len(lines), out.String())
+	}
+	var responses []replResponse
+	for _, line := range lines {
+		var r replResponse
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("response is not JSON: %q (%v)", line, err)
+		}
+		responses = append(responses, r)
+	}
+
+	first := responses[0]
+	if first.ID != float64(1) || first.SQL != "SELECT id, email FROM users LIMIT 10;" || strings.Join(first.Columns, ",") != "id,email" || first.RowCount != 1 || first.Rows[0]["email"] != "a@example.com" || first.Error != "" {
+		t.Fatalf("unexpected first response: %+v", first)
+	}
+	if !strings.HasPrefix(responses[1].Error, "invalid request") {
+		t.Fatalf("expected an invalid request error, got %+v", responses[1])
+	}
+	if responses[2].ID != "b" || responses[2].Error == "" || responses[2].Rows == nil {
+		t.Fatalf("expected a query error with empty rows, got %+v", responses[2])
+	}
+	if responses[3].ID != float64(3) || !strings.Contains(responses[3].Error, `"query"`) {
+		t.Fatalf("expected a missing query error, got %+v", responses[3])
+	}
+	if len(llm.queries) != 2 {
+		t.Fatalf("expected two LLM calls, got %q", llm.queries)
+	}
+}
+
+func TestServeJSONREPLKeepsOnlySuccessfulTurns(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE users (id INTEGER PRIMARY KEY)`)
+	conn, err := openSession(context.Background(), db, nil)
+	if err != nil {
+		t.Fatalf("openSession returned error: %v", err)
+	}
+	defer conn.Close()
+
+	llm := &turnRecordingLLMClient{replies: []string{"SELECT * FROM missing", "SELECT id FROM users", "SELECT count(*) FROM users"}}
+	cfg := Config{Mode: modeChat, DBType: "sqlite", Output: "table", Limit: 10, Timeout: 5 * time.Second, NoHistory: true, ContextTurns: 5, LLMClient: llm}
+	in := strings.NewReader(`{"query": "broken"}` + "\n" + `{"query": "list users"}` + "\n" + `{"query": "count them"}` + "\n")
+
+	if _, err := serveJSONREPL(cfg, db, conn, "", in, &bytes.Buffer{}); err != nil {
+		t.Fatalf("serveJSONREPL returned error: %v", err)
+	}
+	if len(llm.turns) != 3 || len(llm.turns[1]) != 0 {
+		t.Fatalf("expected the failed query to stay out of the context, got %+v", llm.turns)
+	}
+	if len(llm.turns[2]) != 1 || llm.turns[2][0].Query != "list users" {
+		t.Fatalf("expected only the successful turn as context, got %+v", llm.turns[2])
+	}
+}
@@ -0,0 +1,131 @@
+package dbquery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Client struct {
+	cfg           Config
+	db            *sql.DB
+	schemaDB      *sql.DB
+	schemaContext string
+}
+
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	if strings.TrimSpace(cfg.DBURL) == "" {
+		return nil, wrapError(ErrConfig, errors.New("DBURL is required"))
+	}
+
+	dbType, err := resolveDBType(cfg.DBType, cfg.DBURL)
+	if err != nil {
+		return nil, wrapError(ErrConfig, err)
+	}
+	cfg.DBType = dbType
+	cfg.llmHTTP = &sharedLLMHTTPClient{}
+	if strings.TrimSpace(cfg.APIKey) == "" && cfg.LLMClient == nil && normalizeLLMProvider(cfg.LLMProvider) != providerMock {
+		return nil, wrapError(ErrMissingAPIKey, errors.New("missing API key: set Config.APIKey"))
+	}
+	if cfg.Timeout <= 0 {
+		return nil, wrapError(ErrConfig, errors.New("Timeout must be > 0"))
+	}
+	if cfg.SchemaMaxTables <= 0 {
+		return nil, wrapError(ErrConfig, errors.New("SchemaMaxTables must be > 0"))
+	}
+	if cfg.MaxPlanCost < 0 || (cfg.MaxPlanCost > 0 && sqlDialect(cfg.DBType) == "sqlite") {
+		return nil, wrapError(ErrConfig, errors.New("MaxPlanCost must be >= 0 and is only supported for postgres and mysql"))
+	}
+	if strings.TrimSpace(cfg.ExamplesFile) != "" && cfg.examples == nil {
+		if cfg.examples, err = loadPromptExamples(strings.TrimSpace(cfg.ExamplesFile)); err != nil {
+			return nil, wrapError(ErrConfig, err)
+		}
+	}
+
+	db, schemaDB, err := openQueryDatabases(ctx, cfg)
+	if err != nil {
+		return nil, wrapError(ErrDBConnect, fmt.Errorf("open database: %w", err))
+	}
+	detectDBVersion(ctx, schemaDB, &cfg)
+
+	schemaContext, err := buildSchemaContext(ctx, schemaDB, cfg)
+	if err != nil {
+		_ = closeDatabases(db, schemaDB)
+		return nil, wrapError(ErrQuery, fmt.Errorf("build schema context: %w", err))
+	}
+
+	return &Client{cfg: cfg, db: db, schemaDB: schemaDB, schemaContext: schemaContext}, nil
+}
+
+func (c *Client) Close() error {
+	return closeDatabases(c.db, c.schemaDB)
+}
+
+func (c *Client) SchemaContext() string {
+	return c.schemaContext
+}
+
+func (c *Client) GenerateSQL(ctx context.Context, nlQuery string) (string, error) {
+	sqlQuery, err := c.generateSQL(ctx, nlQuery)
+	if err != nil {
+		return "", err
+	}
+	return c.limitSQL(sqlQuery), nil
+}
+
+func (c *Client) generateSQL(ctx context.Context, nlQuery string) (string, error) {
+	schemaContext, prompt := applyQueryHints(c.schemaContext, nlQuery)
+	sqlQuery, err := generateSQLWithRetry(ctx, c.cfg, schemaContext, prompt, nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if !c.cfg.AllowWrite {
+		if err := checkReadOnlySQL(sqlQuery, forbiddenKeywordPattern(c.cfg.DenyKeywords, c.cfg.AllowKeywords), c.cfg.ExplainRejection); err != nil {
+			return "", err
+		}
+	} else if len(c.cfg.AllowWriteTables) > 0 {
+		if err := ensureWriteTablesAllowed(sqlQuery, c.cfg.AllowWriteTables); err != nil {
+			return "", err
+		}
+	}
+	return sqlQuery, nil
+}
+
+func (c *Client) limitSQL(sqlQuery string) string {
+	if c.cfg.NoAutoLimit {
+		return sqlQuery
+	}
+	return ensureLimit(sqlQuery, c.cfg.Limit, sqlDialect(c.cfg.DBType))
+}
+
+func (c *Client) Query(ctx context.Context, nlQuery string) ([]string, []map[string]any, error) {
+	unlimited, err := c.generateSQL(ctx, nlQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+	sqlQuery := c.limitSQL(unlimited)
+
+	var db DBTX = c.db
+	if len(c.cfg.PreSQL) > 0 {
+		session, err := openSession(ctx, c.db, c.cfg.PreSQL)
+		if err != nil {
+			return nil, nil, wrapError(ErrQuery, err)
+		}
+		defer session.Close()
+		db = session
+	}
+
+	if err := checkQueryGuards(ctx, db, c.cfg, sqlQuery, unlimited, true); err != nil {
+		return nil, nil, err
+	}
+
+	columns, rows, err := executeQuery(ctx, db, sqlQuery, timeFormatsFromConfig(c.cfg))
+	if err != nil {
+		return nil, nil, wrapError(ErrQuery, fmt.Errorf("execute SQL query: %w", err))
+	}
+	applyColumnOptions(c.cfg, columns, rows)
+	return columns, rows, nil
+}
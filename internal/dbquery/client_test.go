@@ -0,0 +1,102 @@
+package dbquery
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func newTestLLMServer(t *testing.T, content string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp chatCompletionResponse
+		resp.Choices = append(resp.Choices, struct {
+			Message chatMessage `json:"message"`
+		}{Message: chatMessage{Role: "assistant", Content: content}})
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestSQLiteFile(t *testing.T, statements ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open sqlite file: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("exec %q: %v", stmt, err)
+		}
+	}
+	return path
+}
+
+func TestClientQuery(t *testing.T) {
+	srv := newTestLLMServer(t, "```sql\nSELECT email FROM users ORDER BY id\n```")
+	dbPath := newTestSQLiteFile(t,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`,
+		`INSERT INTO users (email) VALUES ('a@example.com'), ('b@example.com')`,
+	)
+
+	cfg := DefaultConfig()
+	cfg.DBType = "sqlite"
+	cfg.DBURL = dbPath
+	cfg.APIKey = "test-key"
+	cfg.LLMBaseURL = srv.URL
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	defer client.Close()
+
+	sqlQuery, err := client.GenerateSQL(ctx, "all user emails")
+	if err != nil {
+		t.Fatalf("GenerateSQL returned error: %v", err)
+	}
+	if sqlQuery != "SELECT email FROM users ORDER BY id LIMIT 10;" {
+		t.Fatalf("unexpected generated SQL: %q", sqlQuery)
+	}
+
+	columns, rows, err := client.Query(ctx, "all user emails")
+	if err != nil {
+		t.Fatalf("Query returned error: %v", err)
+	}
+	if len(columns) != 1 || len(rows) != 2 || rows[1]["email"] != "b@example.com" {
+		t.Fatalf("unexpected query result: %v %+v", columns, rows)
+	}
+}
+
+func TestNewClientRequiresAPIKey(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DBType = "sqlite"
+	cfg.DBURL = ":memory:"
+
+	_, err := NewClient(context.Background(), cfg)
+	if !errors.Is(err, ErrMissingAPIKey) {
+		t.Fatalf("expected ErrMissingAPIKey, got %v", err)
+	}
+}
+
+func TestNewClientRejectsMaxPlanCostForSQLite(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DBType = "sqlite"
+	cfg.DBURL = ":memory:"
+	cfg.APIKey = "test-key"
+	cfg.MaxPlanCost = 100
+
+	_, err := NewClient(context.Background(), cfg)
+	if !errors.Is(err, ErrConfig) {
+		t.Fatalf("expected ErrConfig, got %v", err)
+	}
+}
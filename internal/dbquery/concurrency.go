@@ -0,0 +1,106 @@
+package dbquery
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+type queryOutcome struct {
+	out  capturedOutput
+	err  error
+	done chan struct{}
+}
+
+// capturedOutput records stdout and stderr writes in the order they happened,
+// so a finished query can be replayed as if it had run alone.
+type capturedOutput struct {
+	chunks []capturedChunk
+}
+
+type capturedChunk struct {
+	stderr bool
+	data   []byte
+}
+
+type capturedStream struct {
+	out    *capturedOutput
+	stderr bool
+}
+
+func (w capturedStream) Write(p []byte) (int, error) {
+	w.out.chunks = append(w.out.chunks, capturedChunk{stderr: w.stderr, data: append([]byte(nil), p...)})
+	return len(p), nil
+}
+
+func (c *capturedOutput) replay(stdout, stderr io.Writer) {
+	for _, chunk := range c.chunks {
+		if chunk.stderr {
+			_, _ = stderr.Write(chunk.data)
+		} else {
+			_, _ = stdout.Write(chunk.data)
+		}
+	}
+}
+
+func runQueriesConcurrently(db *sql.DB, cfg Config, schemaContext string, queries []string) error {
+	// Spinners from parallel workers would overwrite each other on stderr.
+	cfg.NoProgress = true
+
+	outcomes := make([]*queryOutcome, len(queries))
+	sem := make(chan struct{}, cfg.Concurrency)
+	for i, q := range queries {
+		o := &queryOutcome{done: make(chan struct{})}
+		outcomes[i] = o
+		go func() {
+			defer close(o.done)
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			o.err = runBufferedQuery(db, cfg, schemaContext, i, q, &o.out)
+		}()
+	}
+
+	var firstErr error
+	failed := 0
+	for i, o := range outcomes {
+		<-o.done
+		printQueryHeader(cfg, i, len(queries), queries[i])
+		o.out.replay(cfg.out(), cfg.errOut())
+		if o.err != nil {
+			fmt.Fprintf(cfg.errOut(), "error: %v\n", o.err)
+			failed++
+			if firstErr == nil {
+				firstErr = o.err
+			}
+		}
+	}
+	if failed > 0 {
+		return wrapError(firstErr, fmt.Errorf("%d of %d queries failed", failed, len(queries)))
+	}
+	return nil
+}
+
+func runBufferedQuery(db *sql.DB, cfg Config, schemaContext string, i int, query string, out *capturedOutput) error {
+	queryCfg, skip, err := queryOutputConfig(cfg, i)
+	if err != nil || skip {
+		return err
+	}
+	queryCfg.stdout = capturedStream{out: out}
+	queryCfg.stderr = capturedStream{out: out, stderr: true}
+
+	var conn DBTX = db
+	if len(cfg.PreSQL) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+		session, err := openSession(ctx, db, cfg.PreSQL)
+		if err != nil {
+			return wrapError(ErrQuery, err)
+		}
+		defer session.Close()
+		conn = session
+	}
+
+	_, err = processNaturalLanguageQuery(context.Background(), conn, queryCfg, schemaContext, query, nil)
+	return err
+}
@@ -0,0 +1,74 @@
+package dbquery
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type slowLLMClient struct {
+	sql      map[string]string
+	delay    map[string]time.Duration
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (c *slowLLMClient) GenerateSQL(_ context.Context, _, naturalQuery string) (string, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(c.delay[naturalQuery])
+	return c.sql[naturalQuery], nil
+}
+
+func TestRunQueriesConcurrentlyPreservesOrder(t *testing.T) {
+	db := openTestSQLite(t)
+	llm := &slowLLMClient{
+		sql:   map[string]string{"first": "SELECT 1 AS n", "second": "SELECT 2 AS n", "third": "SELECT 3 AS n"},
+		delay: map[string]time.Duration{"first": 100 * time.Millisecond, "second": 20 * time.Millisecond},
+	}
+	var out bytes.Buffer
+	cfg := Config{Mode: modeQuery, DBType: "sqlite", Output: "json", Limit: 10, Timeout: 5 * time.Second, NoHistory: true, Concurrency: 3, LLMClient: llm, stdout: &out}
+
+	if err := runQueriesConcurrently(db, cfg, "", []string{"first", "second", "third"}); err != nil {
+		t.Fatalf("runQueriesConcurrently returned error: %v", err)
+	}
+	if llm.peak.Load() < 2 {
+		t.Fatalf("expected LLM calls to overlap, peak in flight was %d", llm.peak.Load())
+	}
+	got := strings.Join(strings.Fields(out.String()), "")
+	if got != `[{"n":1}][{"n":2}][{"n":3}]` {
+		t.Fatalf("expected results in query order, got %s", got)
+	}
+}
+
+func TestRunQueriesConcurrentlyGroupsStderrByQuery(t *testing.T) {
+	db := openTestSQLite(t)
+	llm := &slowLLMClient{
+		sql:   map[string]string{"first": "SELECT 1 AS n", "second": "SELECT 2 AS n"},
+		delay: map[string]time.Duration{"first": 100 * time.Millisecond},
+	}
+	var out, errOut bytes.Buffer
+	cfg := Config{Mode: modeQuery, DBType: "sqlite", Output: "json", Limit: 10, Timeout: 5 * time.Second, NoHistory: true, ShowSQL: true, Concurrency: 2, LLMClient: llm, stdout: &out, stderr: &errOut}
+
+	if err := runQueriesConcurrently(db, cfg, "", []string{"first", "second"}); err != nil {
+		t.Fatalf("runQueriesConcurrently returned error: %v", err)
+	}
+	stderr := errOut.String()
+	last := -1
+	for _, want := range []string{"== [1/2] first", "SELECT 1 AS n", "1 row in", "== [2/2] second", "SELECT 2 AS n", "1 row in"} {
+		i := strings.Index(stderr[last+1:], want)
+		if i < 0 {
+			t.Fatalf("expected %q after position %d in stderr:\n%s", want, last, stderr)
+		}
+		last += 1 + i
+	}
+}
@@ -0,0 +1,73 @@
+package dbquery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// USD per 1K tokens; --price-input/--price-output override these.
+var builtinModelPrices = map[string]modelPrice{
+	"gpt-4o":        {Input: 0.0025, Output: 0.01},
+	"gpt-4o-mini":   {Input: 0.00015, Output: 0.0006},
+	"gpt-4.1":       {Input: 0.002, Output: 0.008},
+	"gpt-4.1-mini":  {Input: 0.0004, Output: 0.0016},
+	"gpt-4.1-nano":  {Input: 0.0001, Output: 0.0004},
+	"gpt-4-turbo":   {Input: 0.01, Output: 0.03},
+	"gpt-3.5-turbo": {Input: 0.0005, Output: 0.0015},
+}
+
+type modelPrice struct {
+	Input  float64
+	Output float64
+}
+
+type costEstimate struct {
+	Model        string
+	PromptTokens int
+	OutputTokens int
+	Price        modelPrice
+	Priced       bool
+}
+
+func lookupModelPrice(model string) (modelPrice, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if p, ok := builtinModelPrices[model]; ok {
+		return p, true
+	}
+	best := ""
+	for name := range builtinModelPrices {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return modelPrice{}, false
+	}
+	return builtinModelPrices[best], true
+}
+
+func estimateLLMCost(cfg Config, messages []chatMessage) costEstimate {
+	e := costEstimate{Model: cfg.Model, OutputTokens: cfg.MaxTokens}
+	for _, m := range messages {
+		e.PromptTokens += estimateTokens(m.Content)
+	}
+	if cfg.PriceInput > 0 || cfg.PriceOutput > 0 {
+		e.Price = modelPrice{Input: cfg.PriceInput, Output: cfg.PriceOutput}
+		e.Priced = true
+		return e
+	}
+	e.Price, e.Priced = lookupModelPrice(cfg.Model)
+	return e
+}
+
+func (e costEstimate) Cost() float64 {
+	return float64(e.PromptTokens)/1000*e.Price.Input + float64(e.OutputTokens)/1000*e.Price.Output
+}
+
+func (e costEstimate) String() string {
+	tokens := fmt.Sprintf("~%d prompt tokens + up to %d completion tokens", e.PromptTokens, e.OutputTokens)
+	if !e.Priced {
+		return fmt.Sprintf("Estimated LLM usage: %s (no price known for model %q; set --price-input/--price-output)", tokens, e.Model)
+	}
+	return fmt.Sprintf("Estimated LLM cost: %s = $%.4f (%s; $%g/$%g per 1K input/output tokens)", tokens, e.Cost(), e.Model, e.Price.Input, e.Price.Output)
+}
@@ -0,0 +1,38 @@
+package dbquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupModelPrice(t *testing.T) {
+	if p, ok := lookupModelPrice("gpt-4o-mini-2024-07-18"); !ok || p != builtinModelPrices["gpt-4o-mini"] {
+		t.Fatalf("expected dated gpt-4o-mini to use the gpt-4o-mini price, got %+v (%v)", p, ok)
+	}
+	if p, ok := lookupModelPrice("GPT-4o"); !ok || p != builtinModelPrices["gpt-4o"] {
+		t.Fatalf("expected case-insensitive match, got %+v (%v)", p, ok)
+	}
+	if _, ok := lookupModelPrice("llama3"); ok {
+		t.Fatal("did not expect a price for an unknown model")
+	}
+}
+
+func TestEstimateLLMCost(t *testing.T) {
+	messages := []chatMessage{
+		{Role: "system", Content: strings.Repeat("a", 4000)},
+		{Role: "user", Content: strings.Repeat("b", 4000)},
+	}
+
+	e := estimateLLMCost(Config{Model: "custom", MaxTokens: 500, PriceInput: 0.01, PriceOutput: 0.02}, messages)
+	if e.PromptTokens != 2000 || e.OutputTokens != 500 || !e.Priced {
+		t.Fatalf("unexpected estimate: %+v", e)
+	}
+	if got := e.String(); !strings.Contains(got, "~2000 prompt tokens + up to 500 completion tokens = $0.0300") {
+		t.Fatalf("unexpected estimate text: %s", got)
+	}
+
+	e = estimateLLMCost(Config{Model: "llama3", MaxTokens: 500}, messages)
+	if e.Priced || !strings.Contains(e.String(), "set --price-input/--price-output") {
+		t.Fatalf("expected an unpriced estimate for an unknown model, got %s", e)
+	}
+}
@@ -3,6 +3,8 @@ package dbquery
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -19,6 +21,7 @@ import (
 
 type DBTX interface {
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
 func openDatabase(ctx context.Context, cfg Config) (*sql.DB, error) {
@@ -40,6 +43,8 @@ func openDatabase(ctx context.Context, cfg Config) (*sql.DB, error) {
 	case "mysql":
 		driverName = "mysql"
 		dsn = strings.TrimSpace(cfg.DBURL)
+	case dbTypeCSV:
+		return openFileDatabase(ctx, cfg.DBURL)
 	default:
 		return nil, fmt.Errorf("unsupported database type %q", cfg.DBType)
 	}
@@ -51,13 +56,17 @@ func openDatabase(ctx context.Context, cfg Config) (*sql.DB, error) {
 
 	db.SetConnMaxLifetime(5 * time.Minute)
 	db.SetMaxIdleConns(2)
-	db.SetMaxOpenConns(4)
+	db.SetMaxOpenConns(max(4, cfg.Concurrency))
 
 	if cfg.DBType == "sqlite" {
 		db.SetMaxIdleConns(1)
 		db.SetMaxOpenConns(1)
 	}
 
+	if cfg.NoPing {
+		return db, nil
+	}
+
 	if err := db.PingContext(ctx); err != nil {
 		_ = db.Close()
 		if cfg.DBType == "sqlite" {
@@ -75,6 +84,99 @@ func openDatabase(ctx context.Context, cfg Config) (*sql.DB, error) {
 	return db, nil
 }
 
+func detectDBVersion(ctx context.Context, db *sql.DB, cfg *Config) {
+	if strings.TrimSpace(cfg.DBVersion) != "" || cfg.NoPing || cfg.DBType == dbTypeCSV {
+		return
+	}
+	version, err := detectServerVersion(ctx, db, cfg.DBType)
+	if err != nil {
+		if cfg.Verbose {
+			fmt.Fprintf(os.Stderr, "warning: could not detect server version: %v\n", err)
+		}
+		return
+	}
+	cfg.detectedDBVersion = version
+	if version != "" && cfg.Verbose {
+		fmt.Fprintf(os.Stderr, "Server: %s\n", describeServer(cfg.DBType, version))
+	}
+}
+
+func detectServerVersion(ctx context.Context, db *sql.DB, dbType string) (string, error) {
+	var query string
+	switch dbType {
+	case "sqlite":
+		query = `SELECT sqlite_version()`
+	case "postgres":
+		query = `SHOW server_version`
+	case "mysql":
+		query = `SELECT VERSION()`
+	default:
+		return "", fmt.Errorf("unsupported db type %q", dbType)
+	}
+
+	var version string
+	if err := db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return "", err
+	}
+	if fields := strings.Fields(version); len(fields) > 0 {
+		return fields[0], nil
+	}
+	return "", nil
+}
+
+func describeServer(dbType, version string) string {
+	product := map[string]string{"sqlite": "SQLite", "postgres": "PostgreSQL", "mysql": "MySQL"}[dbType]
+	if product == "" {
+		product = dbType
+	}
+	if i := strings.Index(strings.ToLower(version), "-mariadb"); i >= 0 {
+		product, version = "MariaDB", version[:i]
+	}
+	return product + " " + version
+}
+
+func serverVersion(cfg Config) string {
+	if v := strings.TrimSpace(cfg.DBVersion); v != "" {
+		return v
+	}
+	return cfg.detectedDBVersion
+}
+
+func openQueryDatabases(ctx context.Context, cfg Config) (execDB, schemaDB *sql.DB, err error) {
+	readURL := strings.TrimSpace(cfg.ReadURL)
+	if readURL == "" || readURL == strings.TrimSpace(cfg.DBURL) {
+		db, err := openDatabase(ctx, cfg)
+		return db, db, err
+	}
+
+	readCfg := cfg
+	readCfg.DBURL = readURL
+	schemaDB, err = openDatabase(ctx, readCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read replica: %w", err)
+	}
+	if !cfg.AllowWrite {
+		return schemaDB, schemaDB, nil
+	}
+
+	execDB, err = openDatabase(ctx, cfg)
+	if err != nil {
+		_ = schemaDB.Close()
+		return nil, nil, err
+	}
+	return execDB, schemaDB, nil
+}
+
+func closeDatabases(execDB, schemaDB *sql.DB) error {
+	err := execDB.Close()
+	if schemaDB != execDB {
+		if cerr := schemaDB.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
 func validateSQLiteLocation(dsn string) error {
 	path, ok := sqlitePathFromDSN(dsn)
 	if !ok {
@@ -159,7 +261,21 @@ func sqlitePathFromDSN(dsn string) (string, bool) {
 	return raw, true
 }
 
-func executeQuery(ctx context.Context, db DBTX, query string) ([]string, []map[string]any, error) {
+type timeFormats struct {
+	Date     string
+	Time     string
+	DateTime string
+}
+
+func timeFormatsFromConfig(cfg Config) timeFormats {
+	return timeFormats{Date: cfg.DateFormat, Time: cfg.TimeFormat, DateTime: cfg.DateTimeFormat}
+}
+
+func executeQuery(ctx context.Context, db DBTX, query string, formats timeFormats) ([]string, []map[string]any, error) {
+	if isWriteStatement(query) && !returnsRows(query) {
+		return executeWrite(ctx, db, query)
+	}
+
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, nil, err
@@ -171,6 +287,15 @@ func executeQuery(ctx context.Context, db DBTX, query string) ([]string, []map[s
 		return nil, nil, err
 	}
 
+	typeNames := make([]string, len(columns))
+	if colTypes, err := rows.ColumnTypes(); err == nil {
+		for i := range colTypes {
+			if i < len(typeNames) {
+				typeNames[i] = colTypes[i].DatabaseTypeName()
+			}
+		}
+	}
+
 	result := make([]map[string]any, 0)
 	for rows.Next() {
 		values := make([]any, len(columns))
@@ -185,7 +310,7 @@ func executeQuery(ctx context.Context, db DBTX, query string) ([]string, []map[s
 
 		row := make(map[string]any, len(columns))
 		for i, col := range columns {
-			row[col] = normalizeDBValue(values[i])
+			row[col] = normalizeColumnValue(values[i], typeNames[i], formats)
 		}
 		result = append(result, row)
 	}
@@ -197,6 +322,178 @@ func executeQuery(ctx context.Context, db DBTX, query string) ([]string, []map[s
 	return columns, result, nil
 }
 
+func openSession(ctx context.Context, db *sql.DB, preSQL []string) (*sql.Conn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range preSQL {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("run --pre-sql %q: %w", stmt, err)
+		}
+	}
+	return conn, nil
+}
+
+func discardSession(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+}
+
+func sessionHoldsData(cfg Config) bool {
+	if cfg.DBType == dbTypeCSV {
+		return true
+	}
+	if cfg.DBType != "sqlite" {
+		return false
+	}
+	_, onDisk := sqlitePathFromDSN(cfg.DBURL)
+	return !onDisk
+}
+
+func isConnectionLost(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone)
+}
+
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+func executeInTransaction(ctx context.Context, db DBTX, cfg Config, query string) ([]string, []map[string]any, error) {
+	beginner, ok := db.(txBeginner)
+	if !ok {
+		return nil, nil, errors.New("--transaction is not supported by this connection")
+	}
+
+	// database/sql rolls a transaction back when its context ends, so only the statement runs under --timeout;
+	// the COMMIT prompt may wait on the user for longer.
+	tx, err := beginner.BeginTx(context.WithoutCancel(ctx), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin transaction: %w", err)
+	}
+
+	columns, rows, err := executeQuery(ctx, tx, query, timeFormatsFromConfig(cfg))
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, nil, err
+	}
+
+	if len(rows) == 1 {
+		if affected, ok := rows[0]["rows_affected"]; ok {
+			fmt.Fprintf(cfg.errOut(), "Transaction pending: %v rows affected.\n", affected)
+		}
+	}
+
+	commit := cfg.Yes
+	if !commit {
+		if !isTerminal(os.Stdin) {
+			_ = tx.Rollback()
+			return nil, nil, errors.New("transaction rolled back: stdin is not a terminal (use --yes to commit)")
+		}
+		commit, err = promptYesNo("COMMIT this transaction? (no = ROLLBACK)", false)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, nil, err
+		}
+	}
+
+	if !commit {
+		if err := tx.Rollback(); err != nil {
+			return nil, nil, fmt.Errorf("rollback transaction: %w", err)
+		}
+		return nil, nil, errors.New("transaction rolled back")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	fmt.Fprintln(cfg.errOut(), "Transaction committed.")
+	return columns, rows, nil
+}
+
+func executeWrite(ctx context.Context, db DBTX, query string) ([]string, []map[string]any, error) {
+	res, err := db.ExecContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := []string{"rows_affected"}
+	row := map[string]any{}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		row["rows_affected"] = nil
+	} else {
+		row["rows_affected"] = affected
+	}
+
+	if id, err := res.LastInsertId(); err == nil && id > 0 {
+		columns = append(columns, "last_insert_id")
+		row["last_insert_id"] = id
+	}
+
+	return columns, []map[string]any{row}, nil
+}
+
+func normalizeColumnValue(v any, typeName string, formats timeFormats) any {
+	if isJSONColumnType(typeName) {
+		if raw, ok := jsonColumnValue(v); ok {
+			return raw
+		}
+	}
+	if elemType, ok := postgresArrayElemType(typeName); ok {
+		if arr, ok := postgresArrayValue(v, elemType); ok {
+			return arr
+		}
+	}
+
+	t, ok := v.(time.Time)
+	if !ok {
+		return normalizeDBValue(v)
+	}
+
+	upper := strings.ToUpper(strings.TrimSpace(typeName))
+	switch {
+	case strings.Contains(upper, "TIMESTAMP"), strings.Contains(upper, "DATETIME"):
+		return t.Format(layoutOrDefault(formats.DateTime, time.RFC3339Nano))
+	case upper == "DATE":
+		return t.Format(layoutOrDefault(formats.Date, "2006-01-02"))
+	case strings.HasPrefix(upper, "TIME"):
+		return t.Format(layoutOrDefault(formats.Time, "15:04:05"))
+	default:
+		return t.Format(layoutOrDefault(formats.DateTime, time.RFC3339Nano))
+	}
+}
+
+func isJSONColumnType(typeName string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(typeName))
+	return upper == "JSON" || upper == "JSONB"
+}
+
+func jsonColumnValue(v any) (json.RawMessage, bool) {
+	var b []byte
+	switch t := v.(type) {
+	case []byte:
+		b = t
+	case string:
+		b = []byte(t)
+	default:
+		return nil, false
+	}
+	if !json.Valid(b) {
+		return nil, false
+	}
+	return json.RawMessage(append([]byte(nil), b...)), true
+}
+
+func layoutOrDefault(layout, fallback string) string {
+	if strings.TrimSpace(layout) == "" {
+		return fallback
+	}
+	return layout
+}
+
 func normalizeDBValue(v any) any {
 	switch t := v.(type) {
 	case nil:
@@ -1,10 +1,13 @@
 package dbquery
 
 import (
+	"context"
+	"database/sql"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestSQLitePathFromDSN(t *testing.T) {
@@ -58,3 +61,284 @@ func TestValidateSQLiteLocation(t *testing.T) {
 		t.Fatalf("expected directory error, got: %v", err)
 	}
 }
+
+func TestExecuteQueryWriteUsesExec(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`)
+	ctx := context.Background()
+
+	columns, rows, err := executeQuery(ctx, db, "INSERT INTO users (email) VALUES ('a@example.com')", timeFormats{})
+	if err != nil {
+		t.Fatalf("executeQuery insert returned error: %v", err)
+	}
+	if len(columns) != 2 || columns[0] != "rows_affected" || columns[1] != "last_insert_id" {
+		t.Fatalf("unexpected write result columns: %v", columns)
+	}
+	if len(rows) != 1 || rows[0]["rows_affected"] != int64(1) || rows[0]["last_insert_id"] != int64(1) {
+		t.Fatalf("unexpected write result rows: %+v", rows)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM users").Scan(&count); err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected inserted row to be persisted, got count=%d", count)
+	}
+
+	columns, rows, err = executeQuery(ctx, db, "INSERT INTO users (email) VALUES ('b@example.com') RETURNING email", timeFormats{})
+	if err != nil {
+		t.Fatalf("executeQuery insert returning returned error: %v", err)
+	}
+	if len(columns) != 1 || columns[0] != "email" || len(rows) != 1 || rows[0]["email"] != "b@example.com" {
+		t.Fatalf("unexpected RETURNING result: %v %+v", columns, rows)
+	}
+}
+
+func TestExecuteInTransactionCommitsWithYes(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, active INTEGER)`,
+		`INSERT INTO users (active) VALUES (0), (0)`,
+	)
+	ctx := context.Background()
+
+	_, rows, err := executeInTransaction(ctx, db, Config{Yes: true}, "UPDATE users SET active = 1")
+	if err != nil {
+		t.Fatalf("executeInTransaction returned error: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["rows_affected"] != int64(2) {
+		t.Fatalf("unexpected transaction result: %+v", rows)
+	}
+
+	var active int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM users WHERE active = 1").Scan(&active); err != nil {
+		t.Fatalf("count active users: %v", err)
+	}
+	if active != 2 {
+		t.Fatalf("expected committed update, got %d active users", active)
+	}
+}
+
+type ctxRecordingBeginner struct {
+	*sql.DB
+	txCtx context.Context
+}
+
+func (b *ctxRecordingBeginner) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	b.txCtx = ctx
+	return b.DB.BeginTx(ctx, opts)
+}
+
+func TestExecuteInTransactionOutlivesQueryTimeout(t *testing.T) {
+	db := &ctxRecordingBeginner{DB: openTestSQLite(t, `CREATE TABLE users (id INTEGER PRIMARY KEY, active INTEGER)`, `INSERT INTO users (active) VALUES (0)`)}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, _, err := executeInTransaction(ctx, db, Config{Yes: true}, "UPDATE users SET active = 1"); err != nil {
+		t.Fatalf("executeInTransaction returned error: %v", err)
+	}
+	if db.txCtx.Done() != nil {
+		t.Fatal("the transaction must not be bound to the --timeout context, or a slow COMMIT answer finds it rolled back")
+	}
+}
+
+func TestNormalizeColumnValueTimeFormats(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC)
+	tests := []struct {
+		typeName string
+		formats  timeFormats
+		want     string
+	}{
+		{typeName: "DATE", want: "2024-01-02"},
+		{typeName: "TIME", want: "15:04:05"},
+		{typeName: "TIMETZ", want: "15:04:05"},
+		{typeName: "TIMESTAMP", want: "2024-01-02T15:04:05.123Z"},
+		{typeName: "TIMESTAMPTZ", want: "2024-01-02T15:04:05.123Z"},
+		{typeName: "DATETIME", want: "2024-01-02T15:04:05.123Z"},
+		{typeName: "", want: "2024-01-02T15:04:05.123Z"},
+		{typeName: "DATE", formats: timeFormats{Date: "02/01/2006"}, want: "02/01/2024"},
+		{typeName: "TIMESTAMP", formats: timeFormats{DateTime: "2006-01-02 15:04"}, want: "2024-01-02 15:04"},
+		{typeName: "TIME", formats: timeFormats{Time: "3:04 PM"}, want: "3:04 PM"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeColumnValue(ts, tt.typeName, tt.formats); got != tt.want {
+			t.Fatalf("normalizeColumnValue(%q, %+v) = %v, want %q", tt.typeName, tt.formats, got, tt.want)
+		}
+	}
+}
+
+func TestTimeFormatsFromConfig(t *testing.T) {
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--api-key", "test", "--db-url", "./app.db", "--query", "q", "--time-format", "15:04", "--date-format", "02/01/2006"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if got := timeFormatsFromConfig(cfg); got != (timeFormats{Date: "02/01/2006", Time: "15:04"}) {
+		t.Fatalf("unexpected time formats: %+v", got)
+	}
+}
+
+func TestExecuteQueryFormatsDateColumns(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE events (day DATE, at DATETIME)`,
+		`INSERT INTO events VALUES ('2024-01-02', '2024-01-02 10:30:00')`,
+	)
+
+	_, rows, err := executeQuery(context.Background(), db, "SELECT day, at FROM events", timeFormats{})
+	if err != nil {
+		t.Fatalf("executeQuery returned error: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["day"] != "2024-01-02" || rows[0]["at"] != "2024-01-02T10:30:00Z" {
+		t.Fatalf("unexpected formatted rows: %+v", rows)
+	}
+}
+
+func TestOpenDatabaseNoPing(t *testing.T) {
+	ctx := context.Background()
+	cfg := Config{DBType: "postgres", DBURL: "postgres://127.0.0.1:1/none?connect_timeout=1", NoPing: true}
+	db, err := openDatabase(ctx, cfg)
+	if err != nil {
+		t.Fatalf("openDatabase with NoPing should not connect: %v", err)
+	}
+	_ = db.Close()
+
+	cfg = Config{DBType: "sqlite", DBURL: filepath.Join(t.TempDir(), "missing", "app.db"), NoPing: true}
+	if _, err := openDatabase(ctx, cfg); err == nil {
+		t.Fatal("expected sqlite path validation to run with NoPing")
+	}
+}
+
+func TestDetectDBVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("create sqlite file: %v", err)
+	}
+	cfg := Config{DBType: "sqlite", DBURL: path, Limit: 10}
+	db, err := openDatabase(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("openDatabase returned error: %v", err)
+	}
+	defer db.Close()
+
+	if got := serverVersion(cfg); got != "" {
+		t.Fatalf("expected no version before detection, got %q", got)
+	}
+	pinned := Config{DBType: "sqlite", DBURL: path, DBVersion: "3.8"}
+	detectDBVersion(context.Background(), db, &pinned)
+	if pinned.detectedDBVersion != "" {
+		t.Fatalf("expected --db-version to skip detection, got %q", pinned.detectedDBVersion)
+	}
+
+	detectDBVersion(context.Background(), db, &cfg)
+	version := serverVersion(cfg)
+	if !strings.HasPrefix(version, "3.") {
+		t.Fatalf("expected a detected sqlite 3.x version, got %q", version)
+	}
+	systemPrompt, _ := buildLLMPrompts(cfg, "", "list users")
+	if !strings.Contains(systemPrompt, "Server: SQLite "+version+".") {
+		t.Fatalf("expected the server version in the system prompt:\n%s", systemPrompt)
+	}
+
+	cfg.DBVersion = "3.8"
+	if got := serverVersion(cfg); got != "3.8" {
+		t.Fatalf("expected --db-version to override detection, got %q", got)
+	}
+	if got := describeServer("mysql", "10.11.6-MariaDB-1:10.11.6+maria~ubu2204"); got != "MariaDB 10.11.6" {
+		t.Fatalf("unexpected MariaDB description %q", got)
+	}
+}
+
+func TestOpenQueryDatabasesReadURL(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	primary := filepath.Join(dir, "primary.db")
+	replica := filepath.Join(dir, "replica.db")
+	for path, table := range map[string]string{primary: "primary_only", replica: "replica_only"} {
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatalf("open %s: %v", path, err)
+		}
+		if _, err := db.ExecContext(ctx, "CREATE TABLE "+table+" (id INTEGER)"); err != nil {
+			t.Fatalf("create table: %v", err)
+		}
+		_ = db.Close()
+	}
+
+	hasTable := func(db DBTX, table string) bool {
+		rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table)
+		if err != nil {
+			t.Fatalf("query sqlite_master: %v", err)
+		}
+		defer rows.Close()
+		return rows.Next()
+	}
+
+	cfg := Config{DBType: "sqlite", DBURL: primary, ReadURL: replica}
+	execDB, schemaDB, err := openQueryDatabases(ctx, cfg)
+	if err != nil {
+		t.Fatalf("openQueryDatabases returned error: %v", err)
+	}
+	if execDB != schemaDB || !hasTable(execDB, "replica_only") {
+		t.Fatal("expected read-only queries to use the read URL")
+	}
+	_ = closeDatabases(execDB, schemaDB)
+
+	cfg.AllowWrite = true
+	execDB, schemaDB, err = openQueryDatabases(ctx, cfg)
+	if err != nil {
+		t.Fatalf("openQueryDatabases returned error: %v", err)
+	}
+	if !hasTable(execDB, "primary_only") || !hasTable(schemaDB, "replica_only") {
+		t.Fatal("expected --allow-write to execute on the primary and introspect the replica")
+	}
+	if err := closeDatabases(execDB, schemaDB); err != nil {
+		t.Fatalf("closeDatabases returned error: %v", err)
+	}
+}
+
+func TestNormalizeColumnValueJSON(t *testing.T) {
+	v := normalizeColumnValue([]byte(`{"k": "v"}`), "JSONB", timeFormats{})
+	rows := []map[string]any{{"meta": v}}
+
+	out, err := renderOutput("json", []string{"meta"}, rows, renderOptions{})
+	if err != nil {
+		t.Fatalf("renderOutput returned error: %v", err)
+	}
+	if strings.Contains(out, `\"`) || !strings.Contains(out, `"k": "v"`) {
+		t.Fatalf("expected embedded JSON object, got:\n%s", out)
+	}
+
+	table := renderTable([]string{"meta"}, rows, renderOptions{})
+	if !strings.Contains(table, `{"k": "v"}`) {
+		t.Fatalf("expected JSON text in table cell, got:\n%s", table)
+	}
+
+	if got := normalizeColumnValue("not json", "JSON", timeFormats{}); got != "not json" {
+		t.Fatalf("invalid JSON should stay a string, got %#v", got)
+	}
+	if got := normalizeColumnValue([]byte(`{"k":1}`), "TEXT", timeFormats{}); got != `{"k":1}` {
+		t.Fatalf("non-JSON columns should not be parsed, got %#v", got)
+	}
+}
+
+func TestOpenSessionRunsPreSQL(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	conn, err := openSession(ctx, db, []string{"PRAGMA foreign_keys = ON", "CREATE TEMP TABLE scratch (n INTEGER)", "INSERT INTO scratch VALUES (7)"})
+	if err != nil {
+		t.Fatalf("openSession returned error: %v", err)
+	}
+	columns, rows, err := executeQuery(ctx, conn, "PRAGMA foreign_keys", timeFormats{})
+	if err != nil || len(rows) != 1 || formatCellValue(rows[0][columns[0]]) != "1" {
+		t.Fatalf("expected session pragma to be set, got rows=%v err=%v", rows, err)
+	}
+	_, rows, err = executeQuery(ctx, conn, "SELECT n FROM scratch", timeFormats{})
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("expected temp table on the same session, got rows=%v err=%v", rows, err)
+	}
+	_ = conn.Close()
+
+	if _, err := openSession(ctx, db, []string{"SET nothing"}); err == nil || !strings.Contains(err.Error(), "--pre-sql") {
+		t.Fatalf("expected failing pre-sql to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package dbquery
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type llmDebugRecord struct {
+	Endpoint     string
+	SystemPrompt string
+	UserPrompt   string
+	Request      []byte
+	Status       int
+	Response     []byte
+	Err          error
+}
+
+func writeLLMDebugLog(cfg Config, rec llmDebugRecord) {
+	if err := appendLLMDebugLog(cfg.DebugLog, cfg.APIKey, rec); err != nil && cfg.Verbose {
+		fmt.Fprintf(cfg.errOut(), "Warning: failed to write debug log: %v\n", err)
+	}
+}
+
+func appendLLMDebugLog(path, apiKey string, rec llmDebugRecord) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s POST %s\n", time.Now().Format(time.RFC3339), rec.Endpoint)
+	b.WriteString("Authorization: Bearer [REDACTED]\n")
+	fmt.Fprintf(&b, "--- system prompt\n%s\n", rec.SystemPrompt)
+	fmt.Fprintf(&b, "--- user prompt\n%s\n", rec.UserPrompt)
+	fmt.Fprintf(&b, "--- request\n%s\n", rec.Request)
+	if rec.Status != 0 {
+		fmt.Fprintf(&b, "--- response (status %d)\n%s\n", rec.Status, rec.Response)
+	}
+	if rec.Err != nil {
+		fmt.Fprintf(&b, "--- error\n%v\n", rec.Err)
+	}
+	b.WriteString("\n")
+
+	text := b.String()
+	if apiKey != "" {
+		text = strings.ReplaceAll(text, apiKey, "[REDACTED]")
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(text); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,453 @@
+package dbquery
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type columnDetail struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	Default  string `json:"default,omitempty"`
+}
+
+type indexDetail struct {
+	Name    string   `json:"name,omitempty"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary"`
+}
+
+type foreignKeyDetail struct {
+	Name       string   `json:"name,omitempty"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+}
+
+type tableDetails struct {
+	Name        string             `json:"table"`
+	Kind        string             `json:"kind"`
+	Columns     []columnDetail     `json:"columns"`
+	Indexes     []indexDetail      `json:"indexes"`
+	ForeignKeys []foreignKeyDetail `json:"foreign_keys"`
+	Checks      []string           `json:"checks"`
+}
+
+func runDescribe(cfg Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	db, schemaDB, err := openQueryDatabases(ctx, cfg)
+	if err != nil {
+		return wrapError(ErrDBConnect, fmt.Errorf("open database: %w", err))
+	}
+	defer closeDatabases(db, schemaDB)
+
+	opts := introspectOptionsFromConfig(cfg)
+	opts.Tables = []string{cfg.DescribeTable}
+	opts.IncludeViews = true
+	opts.Constraints = true
+	tables, _, err := introspectSchema(ctx, schemaDB, cfg.DBType, opts)
+	if err != nil {
+		return wrapError(ErrQuery, fmt.Errorf("introspect table: %w", err))
+	}
+	if len(tables) == 0 {
+		return wrapError(ErrUnknownTable, fmt.Errorf("table %q not found", cfg.DescribeTable))
+	}
+	details, err := introspectTableDetails(ctx, schemaDB, cfg.DBType, tables[0])
+	if err != nil {
+		return wrapError(ErrQuery, fmt.Errorf("describe table: %w", err))
+	}
+
+	if cfg.Output == "json" {
+		payload, err := json.MarshalIndent(details, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode table details: %w", err)
+		}
+		fmt.Fprintln(cfg.out(), string(payload))
+		return nil
+	}
+	fmt.Fprint(cfg.out(), formatTableDetails(details, renderOptionsFromConfig(cfg)))
+	return nil
+}
+
+func introspectTableDetails(ctx context.Context, db *sql.DB, dbType string, table tableDef) (tableDetails, error) {
+	details := tableDetails{
+		Name:        table.Name,
+		Kind:        table.Kind,
+		Columns:     []columnDetail{},
+		Indexes:     []indexDetail{},
+		ForeignKeys: []foreignKeyDetail{},
+		Checks:      table.Checks,
+	}
+	if details.Checks == nil {
+		details.Checks = []string{}
+	}
+
+	var err error
+	switch sqlDialect(dbType) {
+	case "sqlite":
+		err = sqliteTableDetails(ctx, db, &details)
+	case "postgres":
+		err = postgresTableDetails(ctx, db, &details)
+	case "mysql":
+		err = mysqlTableDetails(ctx, db, &details)
+	default:
+		err = fmt.Errorf("unsupported db type %q", dbType)
+	}
+	return details, err
+}
+
+func sqliteTableDetails(ctx context.Context, db *sql.DB, details *tableDetails) error {
+	rows, err := db.QueryContext(ctx, `SELECT name, type, "notnull", COALESCE(dflt_value, ''), pk FROM pragma_table_info(?) ORDER BY cid`, details.Name)
+	if err != nil {
+		return err
+	}
+	var pkColumns []string
+	for rows.Next() {
+		var (
+			c       columnDetail
+			notNull int
+			pk      int
+		)
+		if err := rows.Scan(&c.Name, &c.Type, &notNull, &c.Default, &pk); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		c.Nullable = notNull == 0 && pk == 0
+		details.Columns = append(details.Columns, c)
+		if pk > 0 {
+			for len(pkColumns) < pk {
+				pkColumns = append(pkColumns, "")
+			}
+			pkColumns[pk-1] = c.Name
+		}
+	}
+	if err := closeRows(rows); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx, `SELECT name, "unique", origin FROM pragma_index_list(?) ORDER BY origin <> 'pk', name`, details.Name)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var (
+			idx    indexDetail
+			unique int
+			origin string
+		)
+		if err := rows.Scan(&idx.Name, &unique, &origin); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		idx.Unique = unique != 0
+		idx.Primary = origin == "pk"
+		details.Indexes = append(details.Indexes, idx)
+	}
+	if err := closeRows(rows); err != nil {
+		return err
+	}
+	hasPrimary := false
+	for i := range details.Indexes {
+		hasPrimary = hasPrimary || details.Indexes[i].Primary
+		details.Indexes[i].Columns, err = queryStrings(ctx, db, `SELECT COALESCE(name, '(expression)') FROM pragma_index_info(?) ORDER BY seqno`, details.Indexes[i].Name)
+		if err != nil {
+			return err
+		}
+	}
+	// INTEGER PRIMARY KEY columns alias the rowid and have no entry in pragma_index_list.
+	if !hasPrimary && len(pkColumns) > 0 {
+		details.Indexes = append([]indexDetail{{Columns: pkColumns, Unique: true, Primary: true}}, details.Indexes...)
+	}
+
+	rows, err = db.QueryContext(ctx, `SELECT id, "table", "from", COALESCE("to", '') FROM pragma_foreign_key_list(?) ORDER BY id, seq`, details.Name)
+	if err != nil {
+		return err
+	}
+	lastID := -1
+	for rows.Next() {
+		var (
+			id                 int
+			refTable, from, to string
+		)
+		if err := rows.Scan(&id, &refTable, &from, &to); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if id != lastID {
+			details.ForeignKeys = append(details.ForeignKeys, foreignKeyDetail{RefTable: refTable})
+			lastID = id
+		}
+		fk := &details.ForeignKeys[len(details.ForeignKeys)-1]
+		fk.Columns = append(fk.Columns, from)
+		if to != "" {
+			fk.RefColumns = append(fk.RefColumns, to)
+		}
+	}
+	return closeRows(rows)
+}
+
+func postgresTableDetails(ctx context.Context, db *sql.DB, details *tableDetails) error {
+	relation := quoteTableNameForDialect(details.Name, "postgres")
+	rows, err := db.QueryContext(ctx, `
+		SELECT a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull, COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_attribute a
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`, relation)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var c columnDetail
+		if err := rows.Scan(&c.Name, &c.Type, &c.Nullable, &c.Default); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		details.Columns = append(details.Columns, c)
+	}
+	if err := closeRows(rows); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT i.relname, ix.indisunique, ix.indisprimary, pg_get_indexdef(ix.indexrelid, k, true)
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		CROSS JOIN generate_series(1, ix.indnatts) k
+		WHERE ix.indrelid = $1::regclass
+		ORDER BY ix.indisprimary DESC, i.relname, k`, relation)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var (
+			idx    indexDetail
+			column string
+		)
+		if err := rows.Scan(&idx.Name, &idx.Unique, &idx.Primary, &column); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if n := len(details.Indexes); n == 0 || details.Indexes[n-1].Name != idx.Name {
+			details.Indexes = append(details.Indexes, idx)
+		}
+		last := &details.Indexes[len(details.Indexes)-1]
+		last.Columns = append(last.Columns, column)
+	}
+	if err := closeRows(rows); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT con.conname, con.confrelid::regclass::text, a.attname, ra.attname
+		FROM pg_constraint con
+		CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, refnum, ord)
+		JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+		JOIN pg_attribute ra ON ra.attrelid = con.confrelid AND ra.attnum = k.refnum
+		WHERE con.conrelid = $1::regclass AND con.contype = 'f'
+		ORDER BY con.conname, k.ord`, relation)
+	if err != nil {
+		return err
+	}
+	return scanForeignKeys(rows, details)
+}
+
+func mysqlTableDetails(ctx context.Context, db *sql.DB, details *tableDetails) error {
+	schemaName, tableName := "", details.Name
+	if i := strings.LastIndexByte(details.Name, '.'); i >= 0 {
+		schemaName, tableName = details.Name[:i], details.Name[i+1:]
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT column_name, column_type, is_nullable = 'YES', COALESCE(column_default, '')
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?
+		ORDER BY ordinal_position`, schemaName, tableName)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var c columnDetail
+		if err := rows.Scan(&c.Name, &c.Type, &c.Nullable, &c.Default); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		details.Columns = append(details.Columns, c)
+	}
+	if err := closeRows(rows); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT index_name, non_unique = 0, index_name = 'PRIMARY', COALESCE(column_name, '(expression)')
+		FROM information_schema.statistics
+		WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?
+		ORDER BY index_name <> 'PRIMARY', index_name, seq_in_index`, schemaName, tableName)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var (
+			idx    indexDetail
+			column string
+		)
+		if err := rows.Scan(&idx.Name, &idx.Unique, &idx.Primary, &column); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if n := len(details.Indexes); n == 0 || details.Indexes[n-1].Name != idx.Name {
+			details.Indexes = append(details.Indexes, idx)
+		}
+		last := &details.Indexes[len(details.Indexes)-1]
+		last.Columns = append(last.Columns, column)
+	}
+	if err := closeRows(rows); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT constraint_name, referenced_table_name, column_name, referenced_column_name
+		FROM information_schema.key_column_usage
+		WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?
+		  AND referenced_table_name IS NOT NULL
+		ORDER BY constraint_name, ordinal_position`, schemaName, tableName)
+	if err != nil {
+		return err
+	}
+	return scanForeignKeys(rows, details)
+}
+
+func scanForeignKeys(rows *sql.Rows, details *tableDetails) error {
+	for rows.Next() {
+		var name, refTable, column, refColumn string
+		if err := rows.Scan(&name, &refTable, &column, &refColumn); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if n := len(details.ForeignKeys); n == 0 || details.ForeignKeys[n-1].Name != name {
+			details.ForeignKeys = append(details.ForeignKeys, foreignKeyDetail{Name: name, RefTable: refTable})
+		}
+		fk := &details.ForeignKeys[len(details.ForeignKeys)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.RefColumns = append(fk.RefColumns, refColumn)
+	}
+	return closeRows(rows)
+}
+
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0)
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, closeRows(rows)
+}
+
+func closeRows(rows *sql.Rows) error {
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	return rows.Close()
+}
+
+func formatTableDetails(details tableDetails, opts renderOptions) string {
+	keys := map[string][]string{}
+	for _, idx := range details.Indexes {
+		switch {
+		case idx.Primary:
+			for _, c := range idx.Columns {
+				keys[c] = append(keys[c], "PK")
+			}
+		case idx.Unique && len(idx.Columns) == 1:
+			keys[idx.Columns[0]] = append(keys[idx.Columns[0]], "UNIQUE")
+		}
+	}
+	for _, fk := range details.ForeignKeys {
+		for i, c := range fk.Columns {
+			ref := fk.RefTable
+			if i < len(fk.RefColumns) {
+				ref += "." + fk.RefColumns[i]
+			}
+			keys[c] = append(keys[c], "FK "+ref)
+		}
+	}
+
+	columns := []string{"column", "type", "nullable", "default", "key"}
+	rows := make([]map[string]any, 0, len(details.Columns))
+	for _, c := range details.Columns {
+		nullable := "no"
+		if c.Nullable {
+			nullable = "yes"
+		}
+		rows = append(rows, map[string]any{
+			"column":   c.Name,
+			"type":     c.Type,
+			"nullable": nullable,
+			"default":  c.Default,
+			"key":      strings.Join(keys[c.Name], ", "),
+		})
+	}
+
+	var b strings.Builder
+	kind := details.Kind
+	if kind == "" {
+		kind = relationTable
+	}
+	fmt.Fprintf(&b, "%s%s %q\n", strings.ToUpper(kind[:1]), kind[1:], details.Name)
+	b.WriteString(renderTable(columns, rows, opts))
+	b.WriteByte('\n')
+
+	if len(details.Indexes) > 0 {
+		b.WriteString("Indexes:\n")
+		for _, idx := range details.Indexes {
+			b.WriteString("    ")
+			if idx.Name != "" {
+				fmt.Fprintf(&b, "%q ", idx.Name)
+			}
+			switch {
+			case idx.Primary:
+				b.WriteString("PRIMARY KEY ")
+			case idx.Unique:
+				b.WriteString("UNIQUE ")
+			}
+			fmt.Fprintf(&b, "(%s)\n", strings.Join(idx.Columns, ", "))
+		}
+	}
+	if len(details.ForeignKeys) > 0 {
+		b.WriteString("Foreign keys:\n")
+		for _, fk := range details.ForeignKeys {
+			b.WriteString("    ")
+			if fk.Name != "" {
+				fmt.Fprintf(&b, "%q ", fk.Name)
+			}
+			fmt.Fprintf(&b, "(%s) REFERENCES %s", strings.Join(fk.Columns, ", "), fk.RefTable)
+			if len(fk.RefColumns) > 0 {
+				fmt.Fprintf(&b, "(%s)", strings.Join(fk.RefColumns, ", "))
+			}
+			b.WriteByte('\n')
+		}
+	}
+	if len(details.Checks) > 0 {
+		b.WriteString("Check constraints:\n")
+		for _, c := range details.Checks {
+			fmt.Fprintf(&b, "    %s\n", c)
+		}
+	}
+	return b.String()
+}
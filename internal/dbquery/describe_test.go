@@ -0,0 +1,89 @@
+package dbquery
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunDescribe(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE)`,
+		`CREATE TABLE orders (
+			id INTEGER PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id),
+			status TEXT DEFAULT 'new',
+			total REAL CHECK (total >= 0)
+		)`,
+		`CREATE INDEX idx_orders_user_status ON orders (user_id, status)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("exec %q: %v", stmt, err)
+		}
+	}
+
+	cfg, err := parseConfig(append([]string{"describe", "orders"}, testQueryArgs(t, "--db-url", dbPath, "--output", "json")...))
+	if err != nil {
+		t.Fatalf("parseConfig returned error: %v", err)
+	}
+	if cfg.Mode != modeDescribe || cfg.DescribeTable != "orders" {
+		t.Fatalf("unexpected describe config: mode=%q table=%q", cfg.Mode, cfg.DescribeTable)
+	}
+	var out bytes.Buffer
+	cfg.stdout = &out
+	if err := runDescribe(cfg); err != nil {
+		t.Fatalf("runDescribe returned error: %v", err)
+	}
+	var details tableDetails
+	if err := json.Unmarshal(out.Bytes(), &details); err != nil {
+		t.Fatalf("describe output is not JSON: %v\n%s", err, out.String())
+	}
+	wantColumns := []columnDetail{
+		{Name: "id", Type: "INTEGER"},
+		{Name: "user_id", Type: "INTEGER"},
+		{Name: "status", Type: "TEXT", Nullable: true, Default: "'new'"},
+		{Name: "total", Type: "REAL", Nullable: true},
+	}
+	if !reflect.DeepEqual(details.Columns, wantColumns) {
+		t.Fatalf("unexpected columns:\n got %+v\nwant %+v", details.Columns, wantColumns)
+	}
+	wantIndexes := []indexDetail{
+		{Columns: []string{"id"}, Unique: true, Primary: true},
+		{Name: "idx_orders_user_status", Columns: []string{"user_id", "status"}},
+	}
+	if !reflect.DeepEqual(details.Indexes, wantIndexes) {
+		t.Fatalf("unexpected indexes:\n got %+v\nwant %+v", details.Indexes, wantIndexes)
+	}
+	wantFKs := []foreignKeyDetail{{Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"}}}
+	if !reflect.DeepEqual(details.ForeignKeys, wantFKs) || len(details.Checks) != 1 {
+		t.Fatalf("unexpected keys or checks: %+v %q", details.ForeignKeys, details.Checks)
+	}
+
+	cfg.Output = "table"
+	cfg.DescribeTable = "users"
+	out.Reset()
+	if err := runDescribe(cfg); err != nil {
+		t.Fatalf("runDescribe returned error: %v", err)
+	}
+	for _, want := range []string{`Table "users"`, "PK", "UNIQUE", "Indexes:", `"sqlite_autoindex_users_1" UNIQUE (email)`} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in describe output:\n%s", want, out.String())
+		}
+	}
+
+	cfg.DescribeTable = "missing"
+	if err := runDescribe(cfg); !errors.Is(err, ErrUnknownTable) {
+		t.Fatalf("expected ErrUnknownTable for a missing table, got %v", err)
+	}
+}
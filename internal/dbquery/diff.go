@@ -0,0 +1,138 @@
+package dbquery
+
+import (
+	"fmt"
+	"strings"
+)
+
+type resultSet struct {
+	Columns []string
+	Rows    []map[string]any
+}
+
+type changedRow struct {
+	Before map[string]any
+	After  map[string]any
+}
+
+type resultDiff struct {
+	Added   []map[string]any
+	Removed []map[string]any
+	Changed []changedRow
+}
+
+func (d resultDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func diffResults(prev, cur resultSet, key string) (resultDiff, error) {
+	if key != "" {
+		if !containsColumn(prev.Columns, key) || !containsColumn(cur.Columns, key) {
+			return resultDiff{}, fmt.Errorf("key column %q is not in both result sets", key)
+		}
+		return diffResultsByKey(prev, cur, key), nil
+	}
+
+	var d resultDiff
+	remaining := map[string]int{}
+	for _, row := range prev.Rows {
+		remaining[rowSignature(prev.Columns, row)]++
+	}
+	for _, row := range cur.Rows {
+		sig := rowSignature(prev.Columns, row)
+		if remaining[sig] > 0 {
+			remaining[sig]--
+			continue
+		}
+		d.Added = append(d.Added, row)
+	}
+	for _, row := range prev.Rows {
+		sig := rowSignature(prev.Columns, row)
+		if remaining[sig] > 0 {
+			remaining[sig]--
+			d.Removed = append(d.Removed, row)
+		}
+	}
+	return d, nil
+}
+
+func diffResultsByKey(prev, cur resultSet, key string) resultDiff {
+	var d resultDiff
+	before := make(map[string]map[string]any, len(prev.Rows))
+	for _, row := range prev.Rows {
+		before[formatCellValue(row[key])] = row
+	}
+
+	seen := map[string]struct{}{}
+	for _, row := range cur.Rows {
+		k := formatCellValue(row[key])
+		seen[k] = struct{}{}
+		old, ok := before[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, row)
+		case rowSignature(cur.Columns, old) != rowSignature(cur.Columns, row):
+			d.Changed = append(d.Changed, changedRow{Before: old, After: row})
+		}
+	}
+	for _, row := range prev.Rows {
+		if _, ok := seen[formatCellValue(row[key])]; !ok {
+			d.Removed = append(d.Removed, row)
+		}
+	}
+	return d
+}
+
+func rowSignature(columns []string, row map[string]any) string {
+	parts := make([]string, len(columns))
+	for i, col := range columns {
+		parts[i] = formatCellValue(row[col])
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+func containsColumn(columns []string, name string) bool {
+	for _, col := range columns {
+		if col == name {
+			return true
+		}
+	}
+	return false
+}
+
+func renderDiff(columns []string, d resultDiff, opts renderOptions) string {
+	if d.empty() {
+		return "No changes."
+	}
+
+	diffColumns := append([]string{"change"}, columns...)
+	rows := make([]map[string]any, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+	for _, row := range d.Added {
+		rows = append(rows, diffRow("+", columns, row))
+	}
+	for _, row := range d.Removed {
+		rows = append(rows, diffRow("-", columns, row))
+	}
+	for _, c := range d.Changed {
+		out := diffRow("~", columns, c.After)
+		for _, col := range columns {
+			before, after := formatCellValue(c.Before[col]), formatCellValue(c.After[col])
+			if before != after {
+				out[col] = before + " → " + after
+			}
+		}
+		rows = append(rows, out)
+	}
+
+	summary := fmt.Sprintf("%d added, %d removed, %d changed", len(d.Added), len(d.Removed), len(d.Changed))
+	return renderTable(diffColumns, rows, opts) + "\n" + summary
+}
+
+func diffRow(marker string, columns []string, row map[string]any) map[string]any {
+	out := make(map[string]any, len(columns)+1)
+	out["change"] = marker
+	for _, col := range columns {
+		out[col] = row[col]
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package dbquery
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffResultsFullRow(t *testing.T) {
+	prev := resultSet{Columns: []string{"id", "name"}, Rows: []map[string]any{
+		{"id": 1, "name": "a"}, {"id": 2, "name": "b"}, {"id": 2, "name": "b"},
+	}}
+	cur := resultSet{Columns: []string{"id", "name"}, Rows: []map[string]any{
+		{"id": 1, "name": "a"}, {"id": 2, "name": "b"}, {"id": 3, "name": "c"},
+	}}
+
+	d, err := diffResults(prev, cur, "")
+	if err != nil {
+		t.Fatalf("diffResults returned error: %v", err)
+	}
+	if len(d.Added) != 1 || d.Added[0]["id"] != 3 {
+		t.Fatalf("unexpected added rows: %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0]["id"] != 2 {
+		t.Fatalf("unexpected removed rows: %+v", d.Removed)
+	}
+	if len(d.Changed) != 0 {
+		t.Fatalf("full-row diff should not report changes: %+v", d.Changed)
+	}
+}
+
+func TestDiffResultsByKey(t *testing.T) {
+	prev := resultSet{Columns: []string{"id", "status"}, Rows: []map[string]any{
+		{"id": int64(1), "status": "new"}, {"id": int64(2), "status": "new"},
+	}}
+	cur := resultSet{Columns: []string{"id", "status"}, Rows: []map[string]any{
+		{"id": int64(1), "status": "paid"}, {"id": int64(3), "status": "new"},
+	}}
+
+	d, err := diffResults(prev, cur, "id")
+	if err != nil {
+		t.Fatalf("diffResults returned error: %v", err)
+	}
+	if len(d.Added) != 1 || len(d.Removed) != 1 || len(d.Changed) != 1 {
+		t.Fatalf("unexpected diff: %+v", d)
+	}
+
+	out := renderDiff(cur.Columns, d, renderOptions{})
+	for _, token := range []string{"| +", "| -", "| ~", "new → paid", "1 added, 1 removed, 1 changed"} {
+		if !strings.Contains(out, token) {
+			t.Fatalf("diff output missing %q:\n%s", token, out)
+		}
+	}
+
+	if _, err := diffResults(prev, cur, "missing"); err == nil {
+		t.Fatal("expected error for unknown key column")
+	}
+	if got := renderDiff(cur.Columns, resultDiff{}, renderOptions{}); got != "No changes." {
+		t.Fatalf("unexpected empty diff output: %q", got)
+	}
+}
+
+func TestRerunForDiffAppliesColumnOptions(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, active INTEGER)`,
+		`INSERT INTO users (email, active) VALUES ('alice@example.com', 1)`,
+	)
+	cfg := Config{Timeout: 5 * time.Second, MaskColumns: []string{"email"}, BoolColumns: []string{"active"}}
+
+	got, err := rerunForDiff(db, cfg, "SELECT email, active FROM users")
+	if err != nil {
+		t.Fatalf("rerunForDiff returned error: %v", err)
+	}
+	if got.Rows[0]["email"] == "alice@example.com" || got.Rows[0]["active"] != true {
+		t.Fatalf("expected masked and boolean columns in the diff rerun, got %+v", got.Rows[0])
+	}
+}
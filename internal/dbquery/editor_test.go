@@ -0,0 +1,55 @@
+package dbquery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditSQLInEditor(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sed -i s/users/accounts/")
+
+	got, err := editSQLInEditor("SELECT * FROM users")
+	if err != nil {
+		t.Fatalf("editSQLInEditor returned error: %v", err)
+	}
+	if got != "SELECT * FROM accounts" {
+		t.Fatalf("unexpected edited SQL: %q", got)
+	}
+
+	t.Setenv("EDITOR", "sed -i d")
+	if _, err := editSQLInEditor("SELECT 1"); err == nil {
+		t.Fatal("expected error for empty edited SQL")
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "vim", want: []string{"vim"}},
+		{in: "  code   --wait ", want: []string{"code", "--wait"}},
+		{in: `"/Applications/Sublime Text.app/Contents/SharedSupport/bin/subl" -w`, want: []string{"/Applications/Sublime Text.app/Contents/SharedSupport/bin/subl", "-w"}},
+		{in: `less -R "+G"`, want: []string{"less", "-R", "+G"}},
+		{in: `emacs -nw --eval '(setq x "y")'`, want: []string{"emacs", "-nw", "--eval", `(setq x "y")`}},
+		{in: `/opt/my\ editor/bin/ed "say \"hi\""`, want: []string{"/opt/my editor/bin/ed", `say "hi"`}},
+		{in: `C:\tools\vim.exe`, want: []string{`C:\tools\vim.exe`}},
+		{in: `vim ""`, want: []string{"vim", ""}},
+	}
+	for _, tt := range tests {
+		got, err := splitCommandLine(tt.in)
+		if err != nil {
+			t.Fatalf("splitCommandLine(%q) returned error: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("splitCommandLine(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "   ", `vim "unterminated`, "vim 'x"} {
+		if _, err := splitCommandLine(in); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+	}
+}
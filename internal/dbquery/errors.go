@@ -0,0 +1,69 @@
+package dbquery
+
+import "errors"
+
+var (
+	ErrConfig            = errors.New("invalid configuration")
+	ErrMissingAPIKey     = errors.New("missing API key")
+	ErrDBConnect         = errors.New("database connection failed")
+	ErrLLM               = errors.New("LLM request failed")
+	ErrEmptySQL          = errors.New("LLM returned no SQL")
+	ErrReadOnlyViolation = errors.New("SQL is not read-only")
+	ErrPlanCostExceeded  = errors.New("query plan cost exceeds limit")
+	ErrUnknownTable      = errors.New("SQL references an unknown table")
+	ErrUnboundedScan     = errors.New("query reads a large table without WHERE or LIMIT")
+	ErrQuery             = errors.New("query execution failed")
+	ErrResultAssertion   = errors.New("result assertion failed")
+)
+
+const (
+	ExitOK        = 0
+	ExitFailure   = 1
+	ExitConfig    = 2
+	ExitDBConnect = 3
+	ExitLLM       = 4
+	ExitSafety    = 5
+	ExitQuery     = 6
+	ExitAssertion = 7
+)
+
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+func wrapError(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrConfig), errors.Is(err, ErrMissingAPIKey):
+		return ExitConfig
+	case errors.Is(err, ErrDBConnect):
+		return ExitDBConnect
+	case errors.Is(err, ErrLLM), errors.Is(err, ErrEmptySQL):
+		return ExitLLM
+	case errors.Is(err, ErrReadOnlyViolation), errors.Is(err, ErrPlanCostExceeded), errors.Is(err, ErrUnknownTable), errors.Is(err, ErrUnboundedScan):
+		return ExitSafety
+	case errors.Is(err, ErrQuery):
+		return ExitQuery
+	case errors.Is(err, ErrResultAssertion):
+		return ExitAssertion
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,91 @@
+package dbquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExitCode(t *testing.T) {
+	if got := ExitCode(nil); got != ExitOK {
+		t.Fatalf("expected ExitOK for nil error, got %d", got)
+	}
+	if got := ExitCode(errors.New("boom")); got != ExitFailure {
+		t.Fatalf("expected ExitFailure for plain error, got %d", got)
+	}
+
+	wrapped := fmt.Errorf("outer: %w", wrapError(ErrLLM, errors.New("bad response")))
+	if got := ExitCode(wrapped); got != ExitLLM {
+		t.Fatalf("expected ExitLLM for wrapped error, got %d", got)
+	}
+	if got := ExitCode(wrapError(ErrConfig, wrapError(ErrMissingAPIKey, errors.New("no key")))); got != ExitConfig {
+		t.Fatalf("expected ExitConfig for missing API key, got %d", got)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("context: %w", wrapError(ErrDBConnect, cause))
+
+	if !errors.Is(err, ErrDBConnect) {
+		t.Fatal("expected errors.Is to match ErrDBConnect")
+	}
+	if !errors.Is(err, cause) {
+		t.Fatal("expected errors.Is to match the underlying cause")
+	}
+	if errors.Is(err, ErrLLM) {
+		t.Fatal("did not expect errors.Is to match ErrLLM")
+	}
+
+	var typed *Error
+	if !errors.As(err, &typed) || typed.Kind != ErrDBConnect {
+		t.Fatalf("expected errors.As to return *Error with ErrDBConnect kind, got %+v", typed)
+	}
+	if err.Error() != "context: connection refused" {
+		t.Fatalf("typed error should keep the original message, got %q", err.Error())
+	}
+
+	if !errors.Is(ensureReadOnlySQL("DELETE FROM users"), ErrReadOnlyViolation) {
+		t.Fatal("expected ensureReadOnlySQL to return ErrReadOnlyViolation")
+	}
+}
+
+func TestProcessRawSQLSafetyExitCode(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE users (id INTEGER PRIMARY KEY)`)
+	cfg := Config{Mode: modeQuery, DBType: "sqlite", Output: "table", Limit: 10, Timeout: 5 * time.Second, NoHistory: true}
+
+	_, err := processRawSQL(context.Background(), db, cfg, "DROP TABLE users")
+	if got := ExitCode(err); got != ExitSafety {
+		t.Fatalf("expected ExitSafety, got %d (%v)", got, err)
+	}
+
+	_, err = processRawSQL(context.Background(), db, cfg, "SELECT * FROM missing_table")
+	if got := ExitCode(err); got != ExitQuery {
+		t.Fatalf("expected ExitQuery, got %d (%v)", got, err)
+	}
+}
+
+func TestProcessRawSQLResultAssertions(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE jobs (id INTEGER PRIMARY KEY, status TEXT)`, `INSERT INTO jobs (status) VALUES ('failed')`)
+	cfg := Config{Mode: modeQuery, DBType: "sqlite", Output: "json", Limit: 10, Timeout: 5 * time.Second, NoHistory: true}
+
+	cfg.FailOnEmpty = true
+	if _, err := processRawSQL(context.Background(), db, cfg, "SELECT id FROM jobs WHERE status = 'ok'"); ExitCode(err) != ExitAssertion {
+		t.Fatalf("expected ExitAssertion for an empty result with --fail-on-empty, got %v", err)
+	}
+	if _, err := processRawSQL(context.Background(), db, cfg, "SELECT id FROM jobs"); err != nil {
+		t.Fatalf("expected rows to satisfy --fail-on-empty, got %v", err)
+	}
+
+	cfg.FailOnEmpty, cfg.FailOnRows = false, true
+	_, err := processRawSQL(context.Background(), db, cfg, "SELECT id FROM jobs WHERE status = 'failed'")
+	if ExitCode(err) != ExitAssertion || !strings.Contains(err.Error(), "returned 1 row") {
+		t.Fatalf("expected ExitAssertion for rows with --fail-on-rows, got %v", err)
+	}
+	if _, err := processRawSQL(context.Background(), db, cfg, "SELECT id FROM jobs WHERE status = 'ok'"); err != nil {
+		t.Fatalf("expected an empty result to satisfy --fail-on-rows, got %v", err)
+	}
+}
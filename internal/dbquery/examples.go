@@ -0,0 +1,51 @@
+package dbquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type promptExample struct {
+	Question string `json:"question"`
+	SQL      string `json:"sql"`
+}
+
+func loadPromptExamples(path string) ([]chatTurn, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read --examples-file: %w", err)
+	}
+	var examples []promptExample
+	if err := json.Unmarshal(raw, &examples); err != nil {
+		return nil, fmt.Errorf("parse --examples-file %s: expected a JSON list of {\"question\", \"sql\"} objects: %w", path, err)
+	}
+	turns := make([]chatTurn, 0, len(examples))
+	for i, e := range examples {
+		question, sqlQuery := strings.TrimSpace(e.Question), strings.TrimSpace(e.SQL)
+		if question == "" || sqlQuery == "" {
+			return nil, fmt.Errorf("--examples-file %s: example %d needs both a question and sql", path, i+1)
+		}
+		turns = append(turns, chatTurn{Query: question, SQL: sqlQuery})
+	}
+	return turns, nil
+}
+
+// Examples listed first in the file are kept when the count or token budget cuts the list short.
+func limitPromptExamples(examples []chatTurn, max, maxTokens int) []chatTurn {
+	if max > 0 && len(examples) > max {
+		examples = examples[:max]
+	}
+	if maxTokens <= 0 {
+		return examples
+	}
+	used := 0
+	for i, e := range examples {
+		used += estimateTokens(e.Query) + estimateTokens(e.SQL)
+		if used > maxTokens {
+			return examples[:i]
+		}
+	}
+	return examples
+}
@@ -0,0 +1,55 @@
+package dbquery
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptExamplesInLLMMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "examples.json")
+	examples := `[
+		{"question": "active users", "sql": "SELECT * FROM users WHERE deleted_at IS NULL"},
+		{"question": "orders today", "sql": "SELECT * FROM orders WHERE deleted_at IS NULL AND created_at >= CURRENT_DATE"},
+		{"question": "unused", "sql": "SELECT 1"}
+	]`
+	if err := os.WriteFile(path, []byte(examples), 0o644); err != nil {
+		t.Fatalf("write examples: %v", err)
+	}
+
+	cfg, err := parseConfig(testQueryArgs(t, "--db-url", "app.db", "--api-key", "test", "--query", "q", "--examples-file", path, "--max-examples", "2"))
+	if err != nil {
+		t.Fatalf("parseConfig returned error: %v", err)
+	}
+	turns := []chatTurn{{Query: "list users", SQL: "SELECT * FROM users"}}
+	messages := buildLLMMessages(cfg, "- users (id INTEGER)", "now only the admins", turns)
+
+	var got []string
+	for _, m := range messages[1:] {
+		got = append(got, m.Role+": "+strings.TrimPrefix(strings.SplitN(m.Content, "\n\n", 2)[0], "User request:\n"))
+	}
+	want := []string{
+		"user: active users",
+		"assistant: SELECT * FROM users WHERE deleted_at IS NULL",
+		"user: orders today",
+		"assistant: SELECT * FROM orders WHERE deleted_at IS NULL AND created_at >= CURRENT_DATE",
+		"user: list users",
+		"assistant: SELECT * FROM users",
+		"user: now only the admins",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected messages:\n%s", strings.Join(got, "\n"))
+	}
+
+	if limited := limitPromptExamples(cfg.examples, 0, estimateTokens("active users")+estimateTokens(cfg.examples[0].SQL)); len(limited) != 1 {
+		t.Fatalf("expected the token budget to keep only the first example, got %+v", limited)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"question": "no sql"}]`), 0o644); err != nil {
+		t.Fatalf("write examples: %v", err)
+	}
+	if _, err := parseConfig(testQueryArgs(t, "--db-url", "app.db", "--api-key", "test", "--query", "q", "--examples-file", path)); err == nil || !strings.Contains(err.Error(), "example 1") {
+		t.Fatalf("expected an error for an example without sql, got %v", err)
+	}
+}
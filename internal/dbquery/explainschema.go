@@ -0,0 +1,84 @@
+package dbquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type tableDescriber interface {
+	DescribeTable(ctx context.Context, tableDefinition string) (string, error)
+}
+
+func runExplainSchema(cfg Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	db, schemaDB, err := openQueryDatabases(ctx, cfg)
+	if err != nil {
+		return wrapError(ErrDBConnect, fmt.Errorf("open database: %w", err))
+	}
+	defer closeDatabases(db, schemaDB)
+	detectDBVersion(ctx, schemaDB, &cfg)
+
+	opts := introspectOptionsFromConfig(cfg)
+	opts.Tables = []string{cfg.ExplainSchema}
+	opts.IncludeViews = true
+	opts.Constraints = true
+	tables, _, err := introspectSchema(ctx, schemaDB, cfg.DBType, opts)
+	if err != nil {
+		return wrapError(ErrQuery, fmt.Errorf("introspect table: %w", err))
+	}
+	if len(tables) == 0 {
+		return wrapError(ErrUnknownTable, fmt.Errorf("table %q not found", cfg.ExplainSchema))
+	}
+	definition := strings.TrimSpace(formatTableLine(tables[0], tables[0].Columns))
+
+	client, err := newLLMClient(cfg)
+	if err != nil {
+		return wrapError(ErrConfig, err)
+	}
+	describer, ok := client.(tableDescriber)
+	if !ok {
+		return wrapError(ErrConfig, errors.New("the configured LLM client cannot describe tables"))
+	}
+
+	stopProgress := startProgress(cfg, "Asking the LLM to describe "+tables[0].Name+"...")
+	description, err := describer.DescribeTable(ctx, definition)
+	stopProgress()
+	if err != nil {
+		return wrapError(ErrLLM, fmt.Errorf("describe table with LLM: %w", err))
+	}
+	description = strings.TrimSpace(description)
+
+	if cfg.Output == "json" {
+		payload, err := json.MarshalIndent(map[string]string{
+			"table":       tables[0].Name,
+			"definition":  definition,
+			"description": description,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode table description: %w", err)
+		}
+		fmt.Println(string(payload))
+		return nil
+	}
+	fmt.Println(definition)
+	fmt.Println()
+	fmt.Println(description)
+	return nil
+}
+
+func buildDescribeTablePrompts(cfg Config, tableDefinition string) (string, string) {
+	systemPrompt := strings.Join([]string{
+		"You are a senior data engineer helping an analyst understand an unfamiliar database.",
+		"Describe what the table likely represents and what each column likely holds, based only on the definition shown.",
+		"Mention likely relationships to other tables when column names suggest them, and say when a meaning is a guess.",
+		"Answer in plain text: one short paragraph for the table, then one line per column as `column: description`.",
+		fmt.Sprintf("Database dialect: %s.", sqlDialect(cfg.DBType)),
+	}, "\n")
+	userPrompt := fmt.Sprintf("Table definition:\n%s\n", tableDefinition)
+	return systemPrompt, userPrompt
+}
@@ -0,0 +1,57 @@
+package dbquery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type describingLLMClient struct {
+	definitions []string
+}
+
+func (c *describingLLMClient) GenerateSQL(context.Context, string, string) (string, error) {
+	return "", errors.New("unexpected SQL generation")
+}
+
+func (c *describingLLMClient) DescribeTable(_ context.Context, tableDefinition string) (string, error) {
+	c.definitions = append(c.definitions, tableDefinition)
+	return "Registered users.", nil
+}
+
+func TestRunExplainSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL); CREATE TABLE orders (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("create tables: %v", err)
+	}
+	_ = db.Close()
+
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", path, "--llm-provider", "mock", "--no-progress", "--explain-schema", "users"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	llm := &describingLLMClient{}
+	cfg.LLMClient = llm
+	if err := runSingleQuery(cfg); err != nil {
+		t.Fatalf("runSingleQuery returned error: %v", err)
+	}
+	if len(llm.definitions) != 1 || !strings.HasPrefix(llm.definitions[0], "- users (id INTEGER") || strings.Contains(llm.definitions[0], "orders") {
+		t.Fatalf("expected only the users definition to be sent, got %q", llm.definitions)
+	}
+
+	cfg.ExplainSchema = "missing"
+	if err := runSingleQuery(cfg); !errors.Is(err, ErrUnknownTable) {
+		t.Fatalf("expected unknown table error, got %v", err)
+	}
+
+	if _, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", path, "--llm-provider", "mock", "--explain-schema", "users", "--query", "list users")); err == nil {
+		t.Fatal("expected --explain-schema with --query to be rejected")
+	}
+}
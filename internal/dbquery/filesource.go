@@ -0,0 +1,346 @@
+package dbquery
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const dbTypeCSV = "csv"
+
+func sqlDialect(dbType string) string {
+	if dbType == dbTypeCSV {
+		return "sqlite"
+	}
+	return dbType
+}
+
+type fileTable struct {
+	Name    string
+	Columns []string
+	Rows    [][]any
+}
+
+func openFileDatabase(ctx context.Context, location string) (*sql.DB, error) {
+	paths := splitAndTrimCSV(location)
+	if len(paths) == 0 {
+		return nil, errors.New("--db-url must list at least one csv/tsv/json file")
+	}
+
+	tables := make([]fileTable, 0, len(paths))
+	seen := map[string]string{}
+	for _, path := range paths {
+		t, err := loadFileTable(path)
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[t.Name]; ok {
+			return nil, fmt.Errorf("files %q and %q both map to table %q", prev, path, t.Name)
+		}
+		seen[t.Name] = path
+		tables = append(tables, t)
+	}
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	// Every new connection to :memory: is a fresh, empty database, so pin one.
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+
+	for _, t := range tables {
+		if err := createFileTable(ctx, db, t); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func loadFileTable(path string) (fileTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileTable{}, fmt.Errorf("open data file: %w", err)
+	}
+	defer f.Close()
+
+	ext := strings.ToLower(filepath.Ext(path))
+	t := fileTable{Name: tableNameFromPath(path)}
+	switch ext {
+	case ".csv", ".tsv":
+		t.Columns, t.Rows, err = readDelimitedRows(f, ext == ".tsv")
+	case ".json":
+		t.Columns, t.Rows, err = readJSONRows(f, false)
+	case ".jsonl", ".ndjson":
+		t.Columns, t.Rows, err = readJSONRows(f, true)
+	default:
+		return fileTable{}, fmt.Errorf("unsupported data file %q (expected .csv, .tsv, .json, .jsonl)", path)
+	}
+	if err != nil {
+		return fileTable{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	if len(t.Columns) == 0 {
+		return fileTable{}, fmt.Errorf("read %s: no columns found", path)
+	}
+	t.Columns = uniqueColumnNames(t.Columns)
+	return t, nil
+}
+
+func readDelimitedRows(r io.Reader, tab bool) ([]string, [][]any, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	if tab {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	rows := make([][]any, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		row := make([]any, len(header))
+		for i := range header {
+			if i < len(record) && record[i] != "" {
+				row[i] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+func readJSONRows(r io.Reader, lines bool) ([]string, [][]any, error) {
+	objects := make([]map[string]any, 0)
+	if lines {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(line), &obj); err != nil {
+				return nil, nil, err
+			}
+			objects = append(objects, obj)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, nil, err
+		}
+	} else if err := json.NewDecoder(r).Decode(&objects); err != nil {
+		return nil, nil, fmt.Errorf("expected a JSON array of objects: %w", err)
+	}
+
+	keys := map[string]struct{}{}
+	for _, obj := range objects {
+		for k := range obj {
+			keys[k] = struct{}{}
+		}
+	}
+	columns := make([]string, 0, len(keys))
+	for k := range keys {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+
+	rows := make([][]any, 0, len(objects))
+	for _, obj := range objects {
+		row := make([]any, len(columns))
+		for i, col := range columns {
+			row[i] = jsonCellValue(obj[col])
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, nil
+}
+
+func jsonCellValue(v any) any {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return t
+	case bool:
+		if t {
+			return int64(1)
+		}
+		return int64(0)
+	case float64:
+		if t == float64(int64(t)) {
+			return int64(t)
+		}
+		return t
+	default:
+		raw, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprintf("%v", t)
+		}
+		return string(raw)
+	}
+}
+
+func createFileTable(ctx context.Context, db *sql.DB, t fileTable) error {
+	types := inferColumnTypes(len(t.Columns), t.Rows)
+	defs := make([]string, len(t.Columns))
+	placeholders := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		defs[i] = quoteSQLiteIdent(col) + " " + types[i]
+		placeholders[i] = "?"
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (%s)", quoteSQLiteIdent(t.Name), strings.Join(defs, ", "))); err != nil {
+		return fmt.Errorf("create table %s: %w", t.Name, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s VALUES (%s)", quoteSQLiteIdent(t.Name), strings.Join(placeholders, ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range t.Rows {
+		args := make([]any, len(row))
+		for i, v := range row {
+			args[i] = convertCellValue(v, types[i])
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("load table %s: %w", t.Name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func inferColumnTypes(n int, rows [][]any) []string {
+	kinds := []string{"", "INTEGER", "REAL", "TEXT"}
+	types := make([]string, n)
+	for i := 0; i < n; i++ {
+		rank := 0
+		for _, row := range rows {
+			rank = max(rank, cellTypeRank(row[i]))
+		}
+		if rank == 0 {
+			rank = 3
+		}
+		types[i] = kinds[rank]
+	}
+	return types
+}
+
+func cellTypeRank(v any) int {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case int64:
+		return 1
+	case float64:
+		return 2
+	case string:
+		s := strings.TrimSpace(t)
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return 1
+		}
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			return 2
+		}
+		return 3
+	default:
+		return 3
+	}
+}
+
+func convertCellValue(v any, kind string) any {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	switch kind {
+	case "INTEGER":
+		if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			return i
+		}
+	case "REAL":
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+func tableNameFromPath(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return sanitizeIdent(base, "data")
+}
+
+func uniqueColumnNames(columns []string) []string {
+	out := make([]string, len(columns))
+	used := map[string]int{}
+	for i, col := range columns {
+		name := sanitizeIdent(col, "col_"+strconv.Itoa(i+1))
+		key := strings.ToLower(name)
+		if n := used[key]; n > 0 {
+			name = name + "_" + strconv.Itoa(n+1)
+		}
+		used[key]++
+		out[i] = name
+	}
+	return out
+}
+
+func sanitizeIdent(s, fallback string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	name := strings.Trim(b.String(), "_")
+	if name == "" {
+		return fallback
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "t_" + name
+	}
+	return name
+}
+
+func quoteSQLiteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
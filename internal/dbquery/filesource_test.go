@@ -0,0 +1,76 @@
+package dbquery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenFileDatabase(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "sales-2024.csv")
+	jsonlPath := filepath.Join(dir, "customers.jsonl")
+	if err := os.WriteFile(csvPath, []byte("\ufeffRegion,Amount,Units,Order Date\nnorth,10.5,3,2024-01-02\nsouth,4,,2024-01-03\nnorth,1.5,2,2024-01-04\n"), 0o644); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	if err := os.WriteFile(jsonlPath, []byte(`{"id": 1, "name": "Ada", "vip": true}`+"\n"+`{"id": 2, "name": "Linus", "tags": ["a"]}`+"\n"), 0o644); err != nil {
+		t.Fatalf("write jsonl: %v", err)
+	}
+
+	cfg := Config{DBType: dbTypeCSV, DBURL: csvPath + "," + jsonlPath, SchemaMaxTables: 10}
+	ctx := context.Background()
+	db, err := openDatabase(ctx, cfg)
+	if err != nil {
+		t.Fatalf("openDatabase returned error: %v", err)
+	}
+	defer db.Close()
+
+	schemaContext, err := buildSchemaContext(ctx, db, cfg)
+	if err != nil {
+		t.Fatalf("buildSchemaContext returned error: %v", err)
+	}
+	for _, want := range []string{
+		"- customers (id INTEGER, name TEXT, tags TEXT, vip INTEGER)",
+		"- sales_2024 (Region TEXT, Amount REAL, Units INTEGER, Order_Date TEXT)",
+	} {
+		if !strings.Contains(schemaContext, want) {
+			t.Fatalf("expected schema context to contain %q, got:\n%s", want, schemaContext)
+		}
+	}
+
+	_, rows, err := executeQuery(ctx, db, "SELECT Region, SUM(Amount) AS total, SUM(Units) AS units FROM sales_2024 GROUP BY Region ORDER BY Region", timeFormats{})
+	if err != nil {
+		t.Fatalf("executeQuery returned error: %v", err)
+	}
+	if len(rows) != 2 || rows[0]["total"] != 12.0 || rows[0]["units"] != int64(5) || rows[1]["units"] != nil {
+		t.Fatalf("unexpected aggregate rows: %+v", rows)
+	}
+
+	_, rows, err = executeQuery(ctx, db, "SELECT tags FROM customers WHERE id = 2", timeFormats{})
+	if err != nil {
+		t.Fatalf("executeQuery returned error: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["tags"] != `["a"]` {
+		t.Fatalf("unexpected nested json value: %+v", rows)
+	}
+}
+
+func TestOpenFileDatabaseRejectsDuplicateTables(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a", "data.csv")
+	b := filepath.Join(dir, "b", "data.tsv")
+	for _, p := range []string{a, b} {
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte("id\n1\n"), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	if _, err := openFileDatabase(context.Background(), a+","+b); err == nil || !strings.Contains(err.Error(), "both map to table") {
+		t.Fatalf("expected duplicate table error, got %v", err)
+	}
+}
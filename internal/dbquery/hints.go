@@ -0,0 +1,77 @@
+package dbquery
+
+import (
+	"regexp"
+	"strings"
+)
+
+var tableDirectivePattern = regexp.MustCompile(`(?i)(?:^|\s)@table:("[^"]+"|[a-z_][a-z0-9_$.]*)`)
+var trailingHintPattern = regexp.MustCompile(`(?:^|\s)--\s*(.*)$`)
+
+type queryHints struct {
+	Query  string
+	Hint   string
+	Tables []string
+}
+
+func parseQueryHints(nlQuery string) queryHints {
+	h := queryHints{Query: nlQuery}
+	if m := trailingHintPattern.FindStringSubmatchIndex(h.Query); m != nil {
+		h.Hint = strings.TrimSpace(h.Query[m[2]:m[3]])
+		h.Query = h.Query[:m[0]]
+	}
+	for _, m := range tableDirectivePattern.FindAllStringSubmatch(h.Query, -1) {
+		h.Tables = append(h.Tables, strings.Trim(m[1], `"`))
+	}
+	h.Query = strings.Join(strings.Fields(tableDirectivePattern.ReplaceAllString(h.Query, " ")), " ")
+	return h
+}
+
+func applyQueryHints(schemaContext, nlQuery string) (string, string) {
+	h := parseQueryHints(nlQuery)
+	if h.Hint == "" && len(h.Tables) == 0 {
+		return schemaContext, nlQuery
+	}
+
+	var instructions []string
+	if len(h.Tables) > 0 {
+		schemaContext = scopeSchemaContext(schemaContext, h.Tables)
+		instructions = append(instructions, "Use only these tables: "+strings.Join(h.Tables, ", ")+".")
+	}
+	if h.Hint != "" {
+		instructions = append(instructions, h.Hint)
+	}
+	return schemaContext, h.Query + "\n\nAdditional instructions:\n- " + strings.Join(instructions, "\n- ")
+}
+
+func scopeSchemaContext(schemaContext string, tables []string) string {
+	filter := makeTableFilter(tables)
+	lines := strings.SplitAfter(schemaContext, "\n")
+	kept := make([]string, 0, len(lines))
+	matched := false
+	discovered := false
+	for _, line := range lines {
+		// Only the introspected listing is scoped; --schema-file notes may use "- " bullets too.
+		switch strings.TrimSpace(line) {
+		case "Discovered schema:":
+			discovered = true
+		case "", "Extra schema context from file:":
+			discovered = false
+		}
+		if discovered && strings.HasPrefix(line, "- ") {
+			name := strings.TrimPrefix(line, "- ")
+			if i := strings.IndexAny(name, " ("); i >= 0 {
+				name = name[:i]
+			}
+			if !allowTableName(name, filter) {
+				continue
+			}
+			matched = true
+		}
+		kept = append(kept, line)
+	}
+	if !matched {
+		return schemaContext
+	}
+	return strings.Join(kept, "")
+}
@@ -0,0 +1,71 @@
+package dbquery
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQueryHints(t *testing.T) {
+	tests := []struct {
+		in     string
+		query  string
+		hint   string
+		tables string
+	}{
+		{in: "top customers", query: "top customers"},
+		{in: "top customers -- use the v2_orders table", query: "top customers", hint: "use the v2_orders table"},
+		{in: "revenue by month @table:orders @table:public.payments", query: "revenue by month", tables: "orders,public.payments"},
+		{in: "@table:users signups this week -- count distinct emails", query: "signups this week", hint: "count distinct emails", tables: "users"},
+		{in: "state-of-the-art users", query: "state-of-the-art users"},
+	}
+
+	for _, tt := range tests {
+		h := parseQueryHints(tt.in)
+		if h.Query != tt.query || h.Hint != tt.hint || strings.Join(h.Tables, ",") != tt.tables {
+			t.Fatalf("parseQueryHints(%q) = %+v", tt.in, h)
+		}
+	}
+}
+
+func TestApplyQueryHints(t *testing.T) {
+	schemaContext := "Discovered schema:\n- orders (id INTEGER)\n- public.v2_orders (id INTEGER, total REAL)\n- users (id INTEGER)\n"
+
+	scoped, prompt := applyQueryHints(schemaContext, "top customers @table:v2_orders -- rank by total")
+	if scoped != "Discovered schema:\n- public.v2_orders (id INTEGER, total REAL)\n" {
+		t.Fatalf("unexpected scoped schema:\n%s", scoped)
+	}
+	if prompt != "top customers\n\nAdditional instructions:\n- Use only these tables: v2_orders.\n- rank by total" {
+		t.Fatalf("unexpected prompt: %q", prompt)
+	}
+
+	if scoped, _ := applyQueryHints(schemaContext, "top customers @table:missing"); scoped != schemaContext {
+		t.Fatalf("expected the full schema when no table matches, got:\n%s", scoped)
+	}
+	if scoped, prompt := applyQueryHints(schemaContext, "top customers"); scoped != schemaContext || prompt != "top customers" {
+		t.Fatalf("expected queries without hints to pass through, got %q", prompt)
+	}
+
+	withNotes := schemaContext + "\nExtra schema context from file:\n- status 'A' means active\n"
+	if scoped, _ := applyQueryHints(withNotes, "open orders @table:orders"); scoped != "Discovered schema:\n- orders (id INTEGER)\n\nExtra schema context from file:\n- status 'A' means active\n" {
+		t.Fatalf("expected --schema-file notes to survive @table scoping, got:\n%s", scoped)
+	}
+}
+
+func TestProcessNaturalLanguageQueryAppliesHints(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE users (id INTEGER PRIMARY KEY)`)
+	llm := &scriptedLLMClient{replies: []string{"SELECT id FROM users"}}
+	cfg := Config{Mode: modeQuery, DBType: "sqlite", Output: "json", Timeout: 5 * time.Second, NoHistory: true, LLMClient: llm}
+
+	entry, err := processNaturalLanguageQuery(context.Background(), db, cfg, "- users (id INTEGER)\n", "list ids -- newest first", nil)
+	if err != nil {
+		t.Fatalf("processNaturalLanguageQuery returned error: %v", err)
+	}
+	if entry.NaturalQuery != "list ids -- newest first" {
+		t.Fatalf("expected history to keep the typed query, got %q", entry.NaturalQuery)
+	}
+	if len(llm.queries) != 1 || llm.queries[0] != "list ids\n\nAdditional instructions:\n- newest first" {
+		t.Fatalf("expected the hint as an instruction, got %q", llm.queries)
+	}
+}
@@ -2,15 +2,25 @@ package dbquery
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
+var historyMu sync.Mutex
+
 type HistoryEntry struct {
 	Timestamp    time.Time `json:"timestamp"`
 	Mode         string    `json:"mode"`
@@ -18,9 +28,20 @@ type HistoryEntry struct {
 	Profile      string    `json:"profile,omitempty"`
 	NaturalQuery string    `json:"natural_query"`
 	SQL          string    `json:"sql,omitempty"`
+	QueryHash    string    `json:"query_hash,omitempty"`
+	SQLHash      string    `json:"sql_hash,omitempty"`
 	Rows         int       `json:"rows"`
 	DurationMs   int64     `json:"duration_ms"`
-	Error        string    `json:"error,omitempty"`
+
+	// Nil when the step never ran, so a 0 ms timing still counts as a sample.
+	LLMDurationMs   *int64 `json:"llm_duration_ms,omitempty"`
+	QueryDurationMs *int64 `json:"query_duration_ms,omitempty"`
+
+	Error      string `json:"error,omitempty"`
+	PromptFile string `json:"prompt_file,omitempty"`
+	Attempt    int    `json:"attempt,omitempty"`
+
+	result *resultSet
 }
 
 func recordHistoryBestEffort(cfg Config, entry HistoryEntry) {
@@ -28,35 +49,189 @@ func recordHistoryBestEffort(cfg Config, entry HistoryEntry) {
 		return
 	}
 
+	path := historyFilePath(cfg)
+	if cfg.RedactHistory {
+		key, err := historyHashKey(path)
+		if err != nil {
+			if cfg.Verbose {
+				fmt.Fprintf(cfg.errOut(), "warning: history entry not written: %v\n", err)
+			}
+			return
+		}
+		entry = redactHistoryEntry(entry, key)
+	}
+	if err := appendHistoryEntry(path, entry); err != nil && cfg.Verbose {
+		fmt.Fprintf(cfg.errOut(), "warning: failed to write history: %v\n", err)
+	}
+}
+
+func redactHistoryEntry(e HistoryEntry, key []byte) HistoryEntry {
+	e.QueryHash = historyHash(key, e.NaturalQuery)
+	e.SQLHash = historyHash(key, e.SQL)
+	e.NaturalQuery = ""
+	e.SQL = ""
+	// Driver and safety errors quote the offending SQL and values.
+	e.Error = historyHash(key, e.Error)
+	return e
+}
+
+// Keyed so that short questions cannot be recovered by hashing guesses.
+func historyHash(key []byte, text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(text))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func historyHashKey(historyPath string) ([]byte, error) {
+	path := historyPath + ".key"
+	key, err := os.ReadFile(path)
+	if err == nil && len(key) > 0 {
+		return key, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read history hash key: %w", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate history hash key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create history directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create history hash key: %w", err)
+	}
+	if _, err := f.Write(key); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("write history hash key: %w", err)
+	}
+	return key, f.Close()
+}
+
+func historyQueryText(e HistoryEntry) string {
+	if e.NaturalQuery != "" || e.QueryHash == "" {
+		return e.NaturalQuery
+	}
+	return e.QueryHash
+}
+
+func historySQLText(e HistoryEntry) string {
+	if e.SQL != "" || e.SQLHash == "" {
+		return e.SQL
+	}
+	return e.SQLHash
+}
+
+func historyFilePath(cfg Config) string {
 	historyFile := strings.TrimSpace(cfg.HistoryFile)
 	if historyFile == "" {
 		historyFile = defaultHistoryFile()
 	}
+	return historyFile
+}
+
+func recordHistoryPromptBestEffort(cfg Config, entry *HistoryEntry, messages []chatMessage) {
+	if !cfg.HistoryFullPrompt || cfg.NoHistory || cfg.Mode == modeHistory {
+		return
+	}
+
+	path, err := writeHistoryPrompt(historyFilePath(cfg)+".prompts", entry.Timestamp, messages)
+	if err != nil {
+		if cfg.Verbose {
+			fmt.Fprintf(cfg.errOut(), "warning: failed to write history prompt: %v\n", err)
+		}
+		return
+	}
+	entry.PromptFile = path
+}
+
+func writeHistoryPrompt(dir string, ts time.Time, messages []chatMessage) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create history prompt directory: %w", err)
+	}
 
-	if err := appendHistoryEntry(historyFile, entry); err != nil && cfg.Verbose {
-		fmt.Fprintf(os.Stderr, "warning: failed to write history: %v\n", err)
+	path := filepath.Join(dir, ts.UTC().Format("20060102T150405.000000000Z")+".txt.gz")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("create history prompt file: %w", err)
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	if _, err := io.WriteString(zw, formatPrompts(messages)); err != nil {
+		return "", fmt.Errorf("write history prompt: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("write history prompt: %w", err)
 	}
+	return path, nil
+}
+
+func readHistoryPrompt(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open history prompt file: %w", err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return "", fmt.Errorf("read history prompt file: %w", err)
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("read history prompt file: %w", err)
+	}
+	return string(raw), nil
 }
 
 func appendHistoryEntry(path string, entry HistoryEntry) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("create history directory: %w", err)
 	}
 
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode history entry: %w", err)
+	}
+
 	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
 	if err != nil {
 		return fmt.Errorf("open history file: %w", err)
 	}
 	defer f.Close()
 
-	enc := json.NewEncoder(f)
-	if err := enc.Encode(entry); err != nil {
-		return fmt.Errorf("encode history entry: %w", err)
+	// The mutex covers goroutines (--concurrency); the file lock covers other dbquery processes.
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("lock history file: %w", err)
+	}
+	defer unlockFile(f)
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write history entry: %w", err)
 	}
 	return nil
 }
 
 func runHistory(cfg Config) error {
+	if cfg.HistoryFollow {
+		return followHistory(context.Background(), cfg.HistoryFile, os.Stdout, cfg.HistoryLimit, cfg.HistoryWidth, historyFollowInterval)
+	}
+
 	entries, err := readHistoryEntries(cfg.HistoryFile)
 	if err != nil {
 		return err
@@ -67,10 +242,17 @@ func runHistory(cfg Config) error {
 		return nil
 	}
 
-	if cfg.HistoryLimit < len(entries) {
+	if cfg.HistoryDedup {
+		entries = dedupHistoryEntries(entries)
+	}
+	if cfg.HistoryLimit > 0 && cfg.HistoryLimit < len(entries) {
 		entries = entries[len(entries)-cfg.HistoryLimit:]
 	}
 
+	if cfg.HistoryStats {
+		return printHistoryStats(cfg, entries)
+	}
+
 	if cfg.HistoryOutput == "json" {
 		payload, err := json.MarshalIndent(entries, "", "  ")
 		if err != nil {
@@ -82,7 +264,7 @@ func runHistory(cfg Config) error {
 
 	columns := []string{"timestamp", "mode", "db", "rows", "ms", "query", "error"}
 	if cfg.HistoryFull {
-		columns = []string{"timestamp", "mode", "db", "rows", "ms", "query", "sql", "error"}
+		columns = []string{"timestamp", "mode", "db", "rows", "ms", "llm_ms", "query_ms", "query", "sql", "prompt", "error"}
 	}
 
 	rows := make([]map[string]any, 0, len(entries))
@@ -93,16 +275,118 @@ func runHistory(cfg Config) error {
 			"db":        e.DBType,
 			"rows":      e.Rows,
 			"ms":        e.DurationMs,
-			"query":     e.NaturalQuery,
+			"query":     truncateDisplay(historyQueryText(e), cfg.HistoryWidth),
 			"error":     e.Error,
 		}
 		if cfg.HistoryFull {
-			row["sql"] = e.SQL
+			row["sql"] = truncateDisplay(strings.Join(strings.Fields(historySQLText(e)), " "), cfg.HistoryWidth)
+			row["prompt"] = e.PromptFile
+			row["llm_ms"] = optionalMs(e.LLMDurationMs)
+			row["query_ms"] = optionalMs(e.QueryDurationMs)
 		}
 		rows = append(rows, row)
 	}
 
-	rendered, err := renderOutput("table", columns, rows)
+	rendered, err := renderOutput("table", columns, rows, renderOptions{})
+	if err != nil {
+		return err
+	}
+	fmt.Println(rendered)
+	return nil
+}
+
+func dedupHistoryEntries(entries []HistoryEntry) []HistoryEntry {
+	seen := make(map[string]struct{}, len(entries))
+	out := make([]HistoryEntry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		key := strings.Join(strings.Fields(historyQueryText(entries[i])), " ")
+		if key == "" {
+			key = "sql:" + strings.Join(strings.Fields(historySQLText(entries[i])), " ")
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, entries[i])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+type historyStats struct {
+	Entries       int     `json:"entries"`
+	Errors        int     `json:"errors"`
+	AvgDurationMs float64 `json:"avg_duration_ms"`
+	AvgLLMMs      float64 `json:"avg_llm_ms"`
+	AvgQueryMs    float64 `json:"avg_query_ms"`
+	LLMSamples    int     `json:"llm_samples"`
+	QuerySamples  int     `json:"query_samples"`
+}
+
+func computeHistoryStats(entries []HistoryEntry) historyStats {
+	var s historyStats
+	var total, llm, query int64
+	for _, e := range entries {
+		s.Entries++
+		total += e.DurationMs
+		if e.Error != "" {
+			s.Errors++
+		}
+		if e.LLMDurationMs != nil {
+			s.LLMSamples++
+			llm += *e.LLMDurationMs
+		}
+		if e.QueryDurationMs != nil {
+			s.QuerySamples++
+			query += *e.QueryDurationMs
+		}
+	}
+	if s.Entries > 0 {
+		s.AvgDurationMs = float64(total) / float64(s.Entries)
+	}
+	if s.LLMSamples > 0 {
+		s.AvgLLMMs = float64(llm) / float64(s.LLMSamples)
+	}
+	if s.QuerySamples > 0 {
+		s.AvgQueryMs = float64(query) / float64(s.QuerySamples)
+	}
+	return s
+}
+
+func elapsedMs(start time.Time) *int64 {
+	ms := time.Since(start).Milliseconds()
+	return &ms
+}
+
+func optionalMs(ms *int64) any {
+	if ms == nil {
+		return ""
+	}
+	return *ms
+}
+
+func printHistoryStats(cfg Config, entries []HistoryEntry) error {
+	stats := computeHistoryStats(entries)
+	if cfg.HistoryOutput == "json" {
+		payload, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal history stats: %w", err)
+		}
+		fmt.Println(string(payload))
+		return nil
+	}
+
+	columns := []string{"entries", "errors", "avg_ms", "avg_llm_ms", "avg_query_ms"}
+	rows := []map[string]any{{
+		"entries":      stats.Entries,
+		"errors":       stats.Errors,
+		"avg_ms":       fmt.Sprintf("%.0f", stats.AvgDurationMs),
+		"avg_llm_ms":   fmt.Sprintf("%.0f", stats.AvgLLMMs),
+		"avg_query_ms": fmt.Sprintf("%.0f", stats.AvgQueryMs),
+	}}
+	rendered, err := renderOutput("table", columns, rows, renderOptions{})
 	if err != nil {
 		return err
 	}
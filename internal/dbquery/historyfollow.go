@@ -0,0 +1,109 @@
+package dbquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const historyFollowInterval = 500 * time.Millisecond
+
+func followHistory(ctx context.Context, path string, out io.Writer, backlog, width int, interval time.Duration) error {
+	entries, err := readHistoryEntries(path)
+	if err != nil {
+		return err
+	}
+	if backlog < len(entries) {
+		entries = entries[len(entries)-backlog:]
+	}
+	for _, e := range entries {
+		fmt.Fprintln(out, formatHistoryLine(e, width))
+	}
+
+	offset := historyFileSize(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		size := historyFileSize(path)
+		if size < offset {
+			offset = 0
+		}
+		if size == offset {
+			continue
+		}
+		chunk, err := readHistoryChunk(path, offset, size)
+		if err != nil {
+			return err
+		}
+		// Only consume complete lines; a partially written entry is picked up on the next poll.
+		end := bytes.LastIndexByte(chunk, '\n')
+		if end < 0 {
+			continue
+		}
+		offset += int64(end + 1)
+		for _, line := range strings.Split(string(chunk[:end]), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			var entry HistoryEntry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: skipping unparsable history line: %v\n", err)
+				continue
+			}
+			fmt.Fprintln(out, formatHistoryLine(entry, width))
+		}
+	}
+}
+
+func historyFileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func readHistoryChunk(path string, offset, size int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open history file: %w", err)
+	}
+	defer f.Close()
+
+	chunk := make([]byte, size-offset)
+	n, err := f.ReadAt(chunk, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read history file: %w", err)
+	}
+	return chunk[:n], nil
+}
+
+func formatHistoryLine(e HistoryEntry, width int) string {
+	query := historyQueryText(e)
+	if query == "" {
+		query = historySQLText(e)
+	}
+	line := fmt.Sprintf("%s  %-5s %-8s %5d %-4s %6dms  %s",
+		e.Timestamp.Local().Format("2006-01-02 15:04:05"), e.Mode, e.DBType, e.Rows, pluralize(e.Rows, "row", "rows"), e.DurationMs,
+		truncateDisplay(strings.Join(strings.Fields(query), " "), width))
+	if e.Error != "" {
+		line += "  error: " + truncateDisplay(strings.Join(strings.Fields(e.Error), " "), width)
+	}
+	return line
+}
@@ -0,0 +1,86 @@
+package dbquery
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestFollowHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, q := range []string{"old query", "recent query"} {
+		if err := appendHistoryEntry(path, HistoryEntry{Timestamp: ts, Mode: modeQuery, DBType: "sqlite", NaturalQuery: q, Rows: 2}); err != nil {
+			t.Fatalf("appendHistoryEntry returned error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() { done <- followHistory(ctx, path, out, 1, 60, 10*time.Millisecond) }()
+	waitForOutput(t, out, "recent query")
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open history: %v", err)
+	}
+	if _, err := f.WriteString(`{"timestamp":"2024-05-01T12:00:01Z","mode":"chat","db_type":"sqlite","natural_query":"new query","rows":1,"duration_ms":5`); err != nil {
+		t.Fatalf("write partial entry: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if strings.Contains(out.String(), "new query") {
+		t.Fatal("did not expect a partially written entry to be printed")
+	}
+	if _, err := f.WriteString(`,"error":"boom"}` + "\n"); err != nil {
+		t.Fatalf("finish entry: %v", err)
+	}
+	_ = f.Close()
+
+	waitForOutput(t, out, "new query")
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("followHistory returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "recent query") || strings.Contains(out.String(), "old query") {
+		t.Fatalf("expected the last backlog entry followed by the new one, got:\n%s", out.String())
+	}
+	if !strings.Contains(lines[1], "chat  sqlite       1 row       5ms  new query  error: boom") {
+		t.Fatalf("unexpected compact line: %q", lines[1])
+	}
+}
+
+func waitForOutput(t *testing.T, out *syncBuffer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %q in output:\n%s", want, out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,225 @@
+package dbquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var planCastPattern = regexp.MustCompile(`(?i)::(?:"[^"]+"|[a-z_][a-z0-9_]*)(?: varying| precision| (?:with|without) time zone)?(?:\[\])?`)
+var predicateColumnPattern = regexp.MustCompile(`(?i)(?:\b([a-z_][a-z0-9_$]*)\.)?\b([a-z_][a-z0-9_$]*)\)*\s*(?:<>|!=|<=|>=|=|<|>|!?~~\*?|\b(?:not\s+)?(?:like|ilike|in|between)\b|\bis\b)`)
+var joinedColumnPattern = regexp.MustCompile(`(?i)(?:<>|!=|<=|>=|=|<|>)\s*\(*\b([a-z_][a-z0-9_$]*)\.([a-z_][a-z0-9_$]*)\b`)
+var tableAliasPattern = regexp.MustCompile("(?i)\\b(?:from|join)\\s+((?:`[^`]+`|\"[^\"]+\"|[a-z_][a-z0-9_$]*)(?:\\.(?:`[^`]+`|\"[^\"]+\"|[a-z_][a-z0-9_$]*))?)(?:\\s+(?:as\\s+)?([a-z_][a-z0-9_]*))?")
+
+var predicateKeywords = map[string]struct{}{
+	"and": {}, "or": {}, "not": {}, "null": {}, "true": {}, "false": {}, "where": {}, "on": {}, "when": {}, "then": {}, "else": {}, "case": {}, "end": {},
+}
+
+var tableAliasStopwords = map[string]struct{}{
+	"where": {}, "join": {}, "on": {}, "using": {}, "left": {}, "right": {}, "inner": {}, "outer": {}, "cross": {}, "full": {}, "natural": {},
+	"straight_join": {}, "group": {}, "order": {}, "having": {}, "limit": {}, "union": {}, "window": {}, "for": {},
+}
+
+type indexSuggestion struct {
+	Table   string
+	Columns []string
+}
+
+type postgresPlanNode struct {
+	NodeType   string             `json:"Node Type"`
+	Relation   string             `json:"Relation Name"`
+	Alias      string             `json:"Alias"`
+	Filter     string             `json:"Filter"`
+	JoinFilter string             `json:"Join Filter"`
+	Plans      []postgresPlanNode `json:"Plans"`
+}
+
+func (s indexSuggestion) SQL(dialect string) string {
+	name := s.Table
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	name = "idx_" + name + "_" + strings.Join(s.Columns, "_")
+	cols := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		cols[i] = quoteIdentForDialect(c, dialect)
+	}
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s);", quoteIdentForDialect(name, dialect), quoteTableNameForDialect(s.Table, dialect), strings.Join(cols, ", "))
+}
+
+func suggestIndexes(ctx context.Context, db DBTX, dbType, query string) ([]indexSuggestion, error) {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	switch dbType {
+	case "postgres":
+		columns, rows, err := executeQuery(ctx, db, "EXPLAIN (FORMAT JSON) "+query, timeFormats{})
+		if err != nil {
+			return nil, fmt.Errorf("explain query: %w", err)
+		}
+		if len(columns) == 0 || len(rows) == 0 {
+			return nil, errors.New("explain query: empty plan")
+		}
+		return postgresIndexSuggestions([]byte(formatCellValue(rows[0][columns[0]])))
+	case "mysql":
+		columns, rows, err := executeQuery(ctx, db, "EXPLAIN "+query, timeFormats{})
+		if err != nil {
+			return nil, fmt.Errorf("explain query: %w", err)
+		}
+		return mysqlIndexSuggestions(query, columns, rows), nil
+	default:
+		return nil, fmt.Errorf("index suggestions are not supported for %s", dbType)
+	}
+}
+
+func printIndexSuggestions(ctx context.Context, db DBTX, cfg Config, query string) {
+	if keyword := leadingKeyword(query); keyword != "select" && keyword != "with" {
+		return
+	}
+	suggestions, err := suggestIndexes(ctx, db, cfg.DBType, query)
+	if err != nil {
+		fmt.Fprintf(cfg.errOut(), "note: no index suggestions: %v\n", err)
+		return
+	}
+	if len(suggestions) == 0 {
+		fmt.Fprintln(cfg.errOut(), "Index suggestions: none (no sequential scans on filtered columns)")
+		return
+	}
+	fmt.Fprintln(cfg.errOut(), "Index suggestions (advisory, not executed):")
+	for _, s := range suggestions {
+		fmt.Fprintf(cfg.errOut(), "  %s\n", s.SQL(sqlDialect(cfg.DBType)))
+	}
+}
+
+func postgresIndexSuggestions(raw []byte) ([]indexSuggestion, error) {
+	var plans []struct {
+		Plan postgresPlanNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil {
+		return nil, fmt.Errorf("parse plan: %w", err)
+	}
+
+	var out []indexSuggestion
+	var walk func(node postgresPlanNode)
+	walk = func(node postgresPlanNode) {
+		if node.NodeType == "Seq Scan" && node.Filter != "" {
+			out = appendIndexSuggestion(out, node.Relation, predicateColumns(node.Filter, node.Alias, true))
+		}
+		// The inner side of a nested loop is rescanned per outer row, so an index on its join columns pays off most.
+		if node.NodeType == "Nested Loop" && node.JoinFilter != "" && len(node.Plans) == 2 {
+			if inner := node.Plans[1]; inner.NodeType == "Seq Scan" {
+				out = appendIndexSuggestion(out, inner.Relation, predicateColumns(node.JoinFilter, inner.Alias, false))
+			}
+		}
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+	for _, p := range plans {
+		walk(p.Plan)
+	}
+	return out, nil
+}
+
+func mysqlIndexSuggestions(query string, columns []string, rows []map[string]any) []indexSuggestion {
+	col := func(row map[string]any, name string) string {
+		for _, c := range columns {
+			if strings.EqualFold(c, name) && row[c] != nil {
+				return formatCellValue(row[c])
+			}
+		}
+		return ""
+	}
+
+	aliases := tableAliases(query)
+	tables := map[string]struct{}{}
+	for _, t := range aliases {
+		tables[strings.ToLower(t)] = struct{}{}
+	}
+	var out []indexSuggestion
+	for _, row := range rows {
+		if !strings.EqualFold(col(row, "type"), "ALL") || col(row, "key") != "" || !strings.Contains(col(row, "Extra"), "Using where") {
+			continue
+		}
+		alias := col(row, "table")
+		table, ok := aliases[strings.ToLower(alias)]
+		if !ok {
+			continue
+		}
+		out = appendIndexSuggestion(out, table, predicateColumns(query, alias, len(tables) == 1))
+	}
+	return out
+}
+
+func tableAliases(query string) map[string]string {
+	masked := maskStringLiterals(query)
+	out := map[string]string{}
+	for _, m := range tableAliasPattern.FindAllStringSubmatchIndex(masked, -1) {
+		if insideFunctionCall(masked, m[0]) {
+			continue
+		}
+		parts := strings.Split(masked[m[2]:m[3]], ".")
+		for i := range parts {
+			parts[i] = unquoteIdent(parts[i])
+		}
+		table := strings.Join(parts, ".")
+		out[strings.ToLower(parts[len(parts)-1])] = table
+		if m[4] >= 0 {
+			alias := strings.ToLower(masked[m[4]:m[5]])
+			if _, stop := tableAliasStopwords[alias]; !stop {
+				out[alias] = table
+			}
+		}
+	}
+	return out
+}
+
+func predicateColumns(expr, alias string, allowUnqualified bool) []string {
+	masked := planCastPattern.ReplaceAllString(maskStringLiterals(expr), "")
+	seen := map[string]struct{}{}
+	var out []string
+	add := func(qualifier, name string) {
+		name = strings.ToLower(name)
+		if _, kw := predicateKeywords[name]; kw {
+			return
+		}
+		if qualifier == "" && !allowUnqualified {
+			return
+		}
+		if qualifier != "" && !strings.EqualFold(qualifier, alias) {
+			return
+		}
+		if _, ok := seen[name]; ok {
+			return
+		}
+		seen[name] = struct{}{}
+		out = append(out, name)
+	}
+	for _, m := range predicateColumnPattern.FindAllStringSubmatchIndex(masked, -1) {
+		if insideFunctionCall(masked, m[4]) {
+			continue
+		}
+		qualifier := ""
+		if m[2] >= 0 {
+			qualifier = masked[m[2]:m[3]]
+		}
+		add(qualifier, masked[m[4]:m[5]])
+	}
+	for _, m := range joinedColumnPattern.FindAllStringSubmatch(masked, -1) {
+		add(m[1], m[2])
+	}
+	return out
+}
+
+func appendIndexSuggestion(out []indexSuggestion, table string, columns []string) []indexSuggestion {
+	if table == "" || len(columns) == 0 {
+		return out
+	}
+	for _, s := range out {
+		if strings.EqualFold(s.Table, table) && strings.EqualFold(strings.Join(s.Columns, ","), strings.Join(columns, ",")) {
+			return out
+		}
+	}
+	return append(out, indexSuggestion{Table: table, Columns: columns})
+}
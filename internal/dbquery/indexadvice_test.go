@@ -0,0 +1,58 @@
+package dbquery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostgresIndexSuggestions(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Nested Loop", "Join Filter": "(o.user_id = u.id)", "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "users", "Alias": "u", "Filter": "(((country)::text = 'DE'::text) AND (lower(email) ~~ '%@x.com'::text))"},
+		{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o", "Filter": "(created_at >= '2024-01-01 00:00:00'::timestamp without time zone)"}
+	]}}]`
+
+	got, err := postgresIndexSuggestions([]byte(plan))
+	if err != nil {
+		t.Fatalf("postgresIndexSuggestions returned error: %v", err)
+	}
+	want := []indexSuggestion{
+		{Table: "orders", Columns: []string{"user_id"}},
+		{Table: "users", Columns: []string{"country"}},
+		{Table: "orders", Columns: []string{"created_at"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected suggestions:\n got %+v\nwant %+v", got, want)
+	}
+	if sql := got[1].SQL("postgres"); sql != `CREATE INDEX "idx_users_country" ON "users" ("country");` {
+		t.Fatalf("unexpected CREATE INDEX: %s", sql)
+	}
+
+	if got, _ := postgresIndexSuggestions([]byte(`[{"Plan": {"Node Type": "Index Scan", "Relation Name": "users", "Alias": "users"}}]`)); len(got) != 0 {
+		t.Fatalf("expected no suggestions for an index scan, got %+v", got)
+	}
+}
+
+func TestMySQLIndexSuggestions(t *testing.T) {
+	query := "SELECT o.id FROM orders o JOIN users u ON o.user_id = u.id WHERE o.status = 'failed' AND u.active = 1"
+	columns := []string{"id", "table", "type", "key", "rows", "Extra"}
+	rows := []map[string]any{
+		{"id": 1, "table": "o", "type": "ALL", "key": nil, "rows": 50000, "Extra": "Using where"},
+		{"id": 1, "table": "u", "type": "eq_ref", "key": "PRIMARY", "rows": 1, "Extra": "Using where"},
+	}
+
+	got := mysqlIndexSuggestions(query, columns, rows)
+	want := []indexSuggestion{{Table: "orders", Columns: []string{"user_id", "status"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected suggestions: got %+v, want %+v", got, want)
+	}
+	if sql := got[0].SQL("mysql"); sql != "CREATE INDEX `idx_orders_user_id_status` ON `orders` (`user_id`, `status`);" {
+		t.Fatalf("unexpected CREATE INDEX: %s", sql)
+	}
+
+	got = mysqlIndexSuggestions("SELECT * FROM events WHERE kind IN ('a', 'b')", columns, []map[string]any{
+		{"table": "events", "type": "ALL", "key": nil, "Extra": "Using where"},
+	})
+	if len(got) != 1 || got[0].Table != "events" || !reflect.DeepEqual(got[0].Columns, []string{"kind"}) {
+		t.Fatalf("expected unqualified columns for a single-table query, got %+v", got)
+	}
+}
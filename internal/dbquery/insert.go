@@ -0,0 +1,109 @@
+package dbquery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func renderSQLInsert(columns []string, rows []map[string]any, opts renderOptions) (string, error) {
+	if strings.TrimSpace(opts.InsertTable) == "" {
+		return "", errors.New("--output sql-insert requires --insert-table")
+	}
+	if len(columns) == 0 {
+		return "", nil
+	}
+
+	quotedColumns := make([]string, len(columns))
+	for i, col := range columns {
+		quotedColumns[i] = quoteIdentForDialect(col, opts.Dialect)
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", quoteTableNameForDialect(opts.InsertTable, opts.Dialect), strings.Join(quotedColumns, ", "))
+
+	lines := make([]string, 0, len(rows))
+	values := make([]string, len(columns))
+	for _, row := range rows {
+		for i, col := range columns {
+			v, err := sqlLiteral(row[col], opts.Dialect)
+			if err != nil {
+				return "", fmt.Errorf("column %s: %w", col, err)
+			}
+			values[i] = v
+		}
+		lines = append(lines, prefix+strings.Join(values, ", ")+");")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func quoteTableNameForDialect(name, dialect string) string {
+	parts := strings.Split(strings.TrimSpace(name), ".")
+	for i, p := range parts {
+		parts[i] = quoteIdentForDialect(p, dialect)
+	}
+	return strings.Join(parts, ".")
+}
+
+func quoteIdentForDialect(name, dialect string) string {
+	if dialect == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func sqlLiteral(v any, dialect string) (string, error) {
+	switch t := normalizeDBValue(v).(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if dialect == "sqlite" {
+			if t {
+				return "1", nil
+			}
+			return "0", nil
+		}
+		if t {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case int:
+		return strconv.Itoa(t), nil
+	case int32:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case uint64:
+		return strconv.FormatUint(t, 10), nil
+	case float32:
+		return sqlFloatLiteral(float64(t), 32)
+	case float64:
+		return sqlFloatLiteral(t, 64)
+	case string:
+		return sqlStringLiteral(t, dialect), nil
+	case json.RawMessage:
+		return sqlStringLiteral(string(t), dialect), nil
+	case []any:
+		return sqlStringLiteral(postgresArrayLiteral(t), dialect), nil
+	case time.Time:
+		return sqlStringLiteral(t.Format(time.RFC3339Nano), dialect), nil
+	default:
+		return sqlStringLiteral(fmt.Sprintf("%v", t), dialect), nil
+	}
+}
+
+func sqlFloatLiteral(f float64, bits int) (string, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", fmt.Errorf("cannot represent %v as a SQL literal", f)
+	}
+	return strconv.FormatFloat(f, 'g', -1, bits), nil
+}
+
+func sqlStringLiteral(s, dialect string) string {
+	if dialect == "mysql" {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,63 @@
+package dbquery
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRenderSQLInsert(t *testing.T) {
+	columns := []string{"id", "name", "active", "score"}
+	rows := []map[string]any{
+		{"id": int64(1), "name": "O'Brien", "active": true, "score": 9.5},
+		{"id": int64(2), "name": nil, "active": false, "score": nil},
+	}
+
+	out, err := renderOutput(outputSQLInsert, columns, rows, renderOptions{InsertTable: "public.people", Dialect: "postgres"})
+	if err != nil {
+		t.Fatalf("renderOutput returned error: %v", err)
+	}
+	want := `INSERT INTO "public"."people" ("id", "name", "active", "score") VALUES (1, 'O''Brien', TRUE, 9.5);` + "\n" +
+		`INSERT INTO "public"."people" ("id", "name", "active", "score") VALUES (2, NULL, FALSE, NULL);`
+	if out != want {
+		t.Fatalf("unexpected postgres inserts:\n%s\nwant:\n%s", out, want)
+	}
+
+	out, err = renderSQLInsert([]string{"path"}, []map[string]any{{"path": `C:\tmp`}}, renderOptions{InsertTable: "files", Dialect: "mysql"})
+	if err != nil {
+		t.Fatalf("renderSQLInsert returned error: %v", err)
+	}
+	if want := "INSERT INTO `files` (`path`) VALUES ('C:\\\\tmp');"; out != want {
+		t.Fatalf("unexpected mysql insert: %s, want %s", out, want)
+	}
+
+	if _, err := renderSQLInsert(columns, rows, renderOptions{}); err == nil {
+		t.Fatal("expected error without insert table")
+	}
+}
+
+func TestSQLInsertRoundTripSQLite(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE src (id INTEGER, note TEXT)`, `INSERT INTO src VALUES (1, 'it''s'), (2, NULL)`)
+
+	columns, rows, err := executeQuery(context.Background(), db, "SELECT id, note FROM src ORDER BY id", timeFormats{})
+	if err != nil {
+		t.Fatalf("executeQuery returned error: %v", err)
+	}
+	out, err := renderSQLInsert(columns, rows, renderOptions{InsertTable: "dst", Dialect: "sqlite"})
+	if err != nil {
+		t.Fatalf("renderSQLInsert returned error: %v", err)
+	}
+
+	if _, err := db.Exec(`CREATE TABLE dst (id INTEGER, note TEXT)`); err != nil {
+		t.Fatalf("create dst: %v", err)
+	}
+	if _, err := db.Exec(out); err != nil {
+		t.Fatalf("exec generated inserts %q: %v", out, err)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM dst d JOIN src s ON s.id = d.id AND s.note IS d.note`).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 round-tripped rows, got %d", n)
+	}
+}
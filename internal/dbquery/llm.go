@@ -3,12 +3,19 @@ package dbquery
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
 type chatMessage struct {
@@ -21,6 +28,45 @@ type chatCompletionRequest struct {
 	Messages    []chatMessage `json:"messages"`
 	Temperature float64       `json:"temperature,omitempty"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
+	TopP        float64       `json:"top_p,omitempty"`
+	Seed        *int64        `json:"seed,omitempty"`
+
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *jsonSchemaSpec `json:"json_schema,omitempty"`
+}
+
+type jsonSchemaSpec struct {
+	Name   string          `json:"name"`
+	Strict bool            `json:"strict"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+var sqlResponseFormat = &responseFormat{
+	Type: "json_schema",
+	JSONSchema: &jsonSchemaSpec{
+		Name:   "sql_query",
+		Strict: true,
+		Schema: json.RawMessage(`{"type":"object","properties":{"sql":{"type":"string"}},"required":["sql"],"additionalProperties":false}`),
+	},
+}
+
+type llmStatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *llmStatusError) Error() string {
+	return fmt.Sprintf("LLM request failed with status %d: %s", e.Status, e.Body)
+}
+
+func (e *llmStatusError) rejectsResponseFormat() bool {
+	body := strings.ToLower(e.Body)
+	return (e.Status == http.StatusBadRequest || e.Status == http.StatusUnprocessableEntity) &&
+		(strings.Contains(body, "response_format") || strings.Contains(body, "json_schema"))
 }
 
 type chatCompletionResponse struct {
@@ -30,90 +76,427 @@ type chatCompletionResponse struct {
 }
 
 var codeFencePattern = regexp.MustCompile("(?s)^```(?:\\w+)?\\s*(.*?)\\s*```$")
+var embeddedCodeFencePattern = regexp.MustCompile("(?s)```(?:sql|SQL)?[ \\t]*\\n(.*?)\\s*```")
+
+type LLMClient interface {
+	GenerateSQL(ctx context.Context, schemaContext, naturalQuery string) (string, error)
+}
+
+type chatTurnsGenerator interface {
+	generateSQLWithTurns(ctx context.Context, schemaContext, naturalQuery string, turns []chatTurn) (string, error)
+}
+
+const (
+	providerOpenAI = "openai"
+	providerMock   = "mock"
+)
+
+func newLLMClient(cfg Config) (LLMClient, error) {
+	if cfg.LLMClient != nil {
+		return cfg.LLMClient, nil
+	}
+
+	switch normalizeLLMProvider(cfg.LLMProvider) {
+	case providerOpenAI:
+		httpClient, err := cfg.llmHTTP.get(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &openAIClient{cfg: cfg, http: httpClient}, nil
+	case providerMock:
+		return &mockLLMClient{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider %q (expected openai|mock)", cfg.LLMProvider)
+	}
+}
+
+func normalizeLLMProvider(v string) string {
+	p := strings.ToLower(strings.TrimSpace(v))
+	if p == "" {
+		return providerOpenAI
+	}
+	return p
+}
+
+func generateSQL(ctx context.Context, cfg Config, schemaContext, naturalQuery string, turns []chatTurn) (string, error) {
+	client, err := newLLMClient(cfg)
+	if err != nil {
+		return "", err
+	}
+	if generator, ok := client.(chatTurnsGenerator); ok && len(turns) > 0 {
+		return generator.generateSQLWithTurns(ctx, schemaContext, naturalQuery, turns)
+	}
+	return client.GenerateSQL(ctx, schemaContext, naturalQuery)
+}
+
+type openAIClient struct {
+	cfg  Config
+	http *http.Client
+}
+
+// sharedLLMHTTPClient is built on first use and shared by every copy of a
+// Config, so repeated LLM calls reuse one transport and its connections.
+type sharedLLMHTTPClient struct {
+	once   sync.Once
+	client *http.Client
+	err    error
+}
 
-func generateSQL(ctx context.Context, cfg Config, schemaContext, naturalQuery string) (string, error) {
+func (s *sharedLLMHTTPClient) get(cfg Config) (*http.Client, error) {
+	if s == nil {
+		return newLLMHTTPClient(cfg)
+	}
+	s.once.Do(func() {
+		s.client, s.err = newLLMHTTPClient(cfg)
+	})
+	return s.client, s.err
+}
+
+func newLLMHTTPClient(cfg Config) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 10
+	transport.MaxIdleConnsPerHost = 4
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.TLSHandshakeTimeout = 10 * time.Second
+
+	if proxy := strings.TrimSpace(cfg.LLMProxy); proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid --llm-proxy %q: expected a URL like http://proxy:8080", proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if caFile := strings.TrimSpace(cfg.LLMCACert); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read --llm-ca-cert: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in --llm-ca-cert %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.LLMHTTPTimeout,
+	}, nil
+}
+
+func (c *openAIClient) GenerateSQL(ctx context.Context, schemaContext, naturalQuery string) (string, error) {
+	return c.generateSQLWithTurns(ctx, schemaContext, naturalQuery, nil)
+}
+
+func (c *openAIClient) generateSQLWithTurns(ctx context.Context, schemaContext, naturalQuery string, turns []chatTurn) (string, error) {
+	messages := buildLLMMessages(c.cfg, schemaContext, naturalQuery, turns)
+	if c.cfg.StructuredOutput {
+		content, err := c.complete(ctx, messages, sqlResponseFormat)
+		var statusErr *llmStatusError
+		if !errors.As(err, &statusErr) || !statusErr.rejectsResponseFormat() {
+			if err != nil {
+				return "", err
+			}
+			if sqlQuery, ok := sqlFromJSON(strings.TrimSpace(content)); ok {
+				return sqlQuery, nil
+			}
+			return content, nil
+		}
+		if c.cfg.Verbose {
+			fmt.Fprintf(c.cfg.errOut(), "provider rejected response_format (status %d); retrying with plain text output\n", statusErr.Status)
+		}
+	}
+	return c.complete(ctx, messages, nil)
+}
+
+func (c *openAIClient) DescribeTable(ctx context.Context, tableDefinition string) (string, error) {
+	systemPrompt, userPrompt := buildDescribeTablePrompts(c.cfg, tableDefinition)
+	return c.complete(ctx, []chatMessage{{Role: "system", Content: systemPrompt}, {Role: "user", Content: userPrompt}}, nil)
+}
+
+func (c *openAIClient) complete(ctx context.Context, messages []chatMessage, format *responseFormat) (string, error) {
+	cfg := c.cfg
 	endpoint := strings.TrimRight(cfg.LLMBaseURL, "/") + "/chat/completions"
+	systemPrompt, userPrompt := messages[0].Content, messages[len(messages)-1].Content
+
+	payload := chatCompletionRequest{
+		Model:       cfg.Model,
+		Messages:    messages,
+		Temperature: cfg.Temperature,
+		MaxTokens:   cfg.MaxTokens,
+		TopP:        cfg.TopP,
+		Seed:        cfg.Seed,
+
+		ResponseFormat: format,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	respBody, status, err := c.post(ctx, endpoint, body)
+	if cfg.DebugLog != "" {
+		writeLLMDebugLog(cfg, llmDebugRecord{
+			Endpoint:     endpoint,
+			SystemPrompt: systemPrompt,
+			UserPrompt:   userPrompt,
+			Request:      body,
+			Status:       status,
+			Response:     respBody,
+			Err:          err,
+		})
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if status < 200 || status >= 300 {
+		return "", &llmStatusError{Status: status, Body: strings.TrimSpace(string(respBody))}
+	}
+
+	var decoded chatCompletionResponse
+	if err := json.Unmarshal(respBody, &decoded); err != nil {
+		return "", fmt.Errorf("decode LLM response: %w", err)
+	}
+
+	if len(decoded.Choices) == 0 {
+		return "", fmt.Errorf("LLM response has no choices")
+	}
 
+	return decoded.Choices[0].Message.Content, nil
+}
+
+const retryEmptyInstruction = "Your previous reply contained no usable SQL. Output only a single SQL statement, nothing else: no prose, no markdown, no refusal."
+
+func retryEmptyQuery(naturalQuery string) string {
+	return naturalQuery + "\n\n" + retryEmptyInstruction
+}
+
+// generateSQLWithRetry returns normalized SQL, re-prompting once under
+// --retry-empty; onRetry sees the unusable first reply before the second call.
+// On ErrEmptySQL after a retry the unusable reply is returned with the error.
+func generateSQLWithRetry(ctx context.Context, cfg Config, schemaContext, prompt string, turns []chatTurn, onRetry func(reply string)) (string, error) {
+	sqlQuery, err := generateSQL(ctx, cfg, schemaContext, prompt, turns)
+	if err != nil {
+		return "", wrapError(ErrLLM, fmt.Errorf("generate SQL with LLM: %w", err))
+	}
+
+	sqlQuery = normalizeSQL(sqlQuery)
+	if cfg.RetryEmpty && !looksLikeSQL(sqlQuery) {
+		if onRetry != nil {
+			onRetry(sqlQuery)
+		}
+		sqlQuery, err = generateSQL(ctx, cfg, schemaContext, retryEmptyQuery(prompt), turns)
+		if err != nil {
+			return "", wrapError(ErrLLM, fmt.Errorf("generate SQL with LLM: %w", err))
+		}
+		sqlQuery = normalizeSQL(sqlQuery)
+		if sqlQuery != "" && !looksLikeSQL(sqlQuery) {
+			return sqlQuery, wrapError(ErrEmptySQL, errors.New("LLM returned no usable SQL after retry"))
+		}
+	}
+	if sqlQuery == "" {
+		return "", wrapError(ErrEmptySQL, errors.New("LLM returned an empty SQL query"))
+	}
+	return sqlQuery, nil
+}
+
+func buildLLMMessages(cfg Config, schemaContext, naturalQuery string, turns []chatTurn) []chatMessage {
+	systemPrompt, userPrompt := buildLLMPrompts(cfg, schemaContext, naturalQuery)
+	messages := []chatMessage{{Role: "system", Content: systemPrompt}}
+	messages = append(messages, chatTurnMessages(limitPromptExamples(cfg.examples, cfg.MaxExamples, cfg.ExamplesMaxTokens))...)
+	messages = append(messages, chatTurnMessages(limitChatTurns(turns, cfg.ContextMaxTokens))...)
+	return append(messages, chatMessage{Role: "user", Content: userPrompt})
+}
+
+func buildLLMPrompts(cfg Config, schemaContext, naturalQuery string) (string, string) {
 	modeLine := "Generate one read-only SQL query."
 	if cfg.AllowWrite {
 		modeLine = "Generate one SQL query matching the request."
+		if len(cfg.AllowWriteTables) > 0 {
+			modeLine += " Only these tables may be modified: " + strings.Join(cfg.AllowWriteTables, ", ") + "."
+		}
 	}
 
-	systemPrompt := strings.Join([]string{
+	limitLine := fmt.Sprintf("Target row limit: %d unless user asks for another limit.", cfg.Limit)
+	if cfg.Limit <= 0 || cfg.NoAutoLimit {
+		limitLine = "Do not add a row limit unless the user asks for one."
+	}
+
+	lines := []string{
 		"You are a senior SQL engineer.",
 		"Translate user requests into valid SQL for the specified dialect.",
 		modeLine,
 		"Use only schema shown in the context.",
 		"Return only raw SQL. No markdown, no explanation, no backticks.",
-		fmt.Sprintf("Target dialect: %s.", cfg.DBType),
-		fmt.Sprintf("Target row limit: %d unless user asks for another limit.", cfg.Limit),
-	}, "\n")
+		fmt.Sprintf("Target dialect: %s.", sqlDialect(cfg.DBType)),
+		limitLine,
+	}
+	if version := serverVersion(cfg); version != "" {
+		lines = append(lines, fmt.Sprintf("Server: %s. Do not use syntax or functions that version does not support.", describeServer(sqlDialect(cfg.DBType), version)))
+	}
+	if quoteLine := quoteIdentifiersInstruction(cfg); quoteLine != "" {
+		lines = append(lines, quoteLine)
+	}
+	systemPrompt := strings.Join(lines, "\n")
 
 	userPrompt := fmt.Sprintf(
 		"User request:\n%s\n\nSchema context:\n%s\n",
 		naturalQuery,
 		schemaContext,
 	)
+	return systemPrompt, userPrompt
+}
 
-	payload := chatCompletionRequest{
-		Model: cfg.Model,
-		Messages: []chatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		Temperature: cfg.Temperature,
-		MaxTokens:   cfg.MaxTokens,
+func formatPrompts(messages []chatMessage) string {
+	parts := make([]string, 0, len(messages))
+	for i, m := range messages {
+		label := "User prompt"
+		switch {
+		case m.Role == "system":
+			label = "System prompt"
+		case m.Role == "assistant":
+			label = "Earlier reply"
+		case i < len(messages)-1:
+			label = "Earlier user request"
+		}
+		parts = append(parts, label+":\n"+m.Content)
 	}
+	return strings.Join(parts, "\n\n")
+}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
+func (c *openAIClient) post(ctx context.Context, endpoint string, body []byte) ([]byte, int, error) {
+	return c.send(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+func (c *openAIClient) send(ctx context.Context, method, endpoint string, body io.Reader) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
 	if err != nil {
-		return "", err
+		return nil, 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	if org := strings.TrimSpace(c.cfg.OpenAIOrg); org != "" {
+		req.Header.Set("OpenAI-Organization", org)
+	}
+	if project := strings.TrimSpace(c.cfg.OpenAIProject); project != "" {
+		req.Header.Set("OpenAI-Project", project)
+	}
+	for _, h := range c.cfg.LLMHeaders {
+		key, value, err := parseLLMHeader(h)
+		if err != nil {
+			return nil, 0, err
+		}
+		req.Header.Set(key, value)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
-		return "", err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, resp.StatusCode, err
 	}
+	return respBody, resp.StatusCode, nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("LLM request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
-	}
+const minOpenAIKeyLength = 40
 
-	var decoded chatCompletionResponse
-	if err := json.Unmarshal(respBody, &decoded); err != nil {
-		return "", fmt.Errorf("decode LLM response: %w", err)
+func apiKeyWarning(key, baseURL string) string {
+	if strings.ContainsAny(key, " \t\r\n\"'") {
+		return "API key contains whitespace or quotes; check for a copy/paste error"
 	}
 
-	if len(decoded.Choices) == 0 {
-		return "", fmt.Errorf("LLM response has no choices")
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || !strings.EqualFold(u.Hostname(), "api.openai.com") {
+		return ""
+	}
+	if !strings.HasPrefix(key, "sk-") {
+		return "API key does not start with \"sk-\" as OpenAI keys do; the provider may reject it"
 	}
+	if len(key) < minOpenAIKeyLength {
+		return fmt.Sprintf("API key is only %d characters and may be truncated", len(key))
+	}
+	return ""
+}
 
-	return decoded.Choices[0].Message.Content, nil
+func parseLLMHeader(h string) (string, string, error) {
+	key, value, ok := strings.Cut(h, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", fmt.Errorf("invalid --llm-header %q (expected \"Key: Value\")", h)
+	}
+	return key, strings.TrimSpace(value), nil
 }
 
 func normalizeSQL(sqlQuery string) string {
 	q := strings.TrimSpace(sqlQuery)
 	if m := codeFencePattern.FindStringSubmatch(q); len(m) == 2 {
 		q = strings.TrimSpace(m[1])
+	} else if m := embeddedCodeFencePattern.FindStringSubmatch(q); len(m) == 2 {
+		q = strings.TrimSpace(m[1])
+	}
+
+	if fromJSON, ok := sqlFromJSON(q); ok {
+		q = strings.TrimSpace(fromJSON)
 	}
 
 	if strings.HasPrefix(strings.ToLower(q), "sql:") {
 		q = strings.TrimSpace(q[4:])
 	}
 
+	if q != "" && !looksLikeSQL(q) {
+		q = stripSQLPreamble(q)
+	}
+
 	q = strings.TrimSpace(q)
 	return q
 }
+
+func sqlFromJSON(s string) (string, bool) {
+	if !strings.HasPrefix(s, "{") {
+		return "", false
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(s), &obj); err != nil {
+		return "", false
+	}
+	for _, key := range []string{"sql", "query"} {
+		if v, ok := obj[key].(string); ok && strings.TrimSpace(v) != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func stripSQLPreamble(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if !looksLikeSQL(line) {
+			continue
+		}
+		q := strings.Join(lines[i:], "\n")
+		if idx := strings.Index(q, ";\n\n"); idx >= 0 && !looksLikeSQL(q[idx+1:]) {
+			q = q[:idx+1]
+		}
+		return q
+	}
+	return s
+}
+
+func estimateTokens(s string) int {
+	if s == "" {
+		return 0
+	}
+	return (len(s) + 3) / 4
+}
@@ -0,0 +1,63 @@
+package dbquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type mockLLMClient struct{}
+
+func (m *mockLLMClient) GenerateSQL(ctx context.Context, schemaContext, naturalQuery string) (string, error) {
+	tables := tablesFromSchemaContext(schemaContext)
+	if len(tables) == 0 {
+		return "SELECT 1 AS mock_result", nil
+	}
+
+	q := strings.ToLower(naturalQuery)
+	table := tables[0]
+	for _, t := range tables {
+		name := strings.ToLower(t)
+		if idx := strings.LastIndex(name, "."); idx >= 0 {
+			name = name[idx+1:]
+		}
+		if strings.Contains(q, name) || strings.Contains(q, strings.TrimSuffix(name, "s")) {
+			table = t
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(q, "count") || strings.Contains(q, "how many"):
+		return fmt.Sprintf("SELECT COUNT(*) AS count FROM %s", table), nil
+	case strings.Contains(q, "latest") || strings.Contains(q, "recent") || strings.Contains(q, "newest"):
+		return fmt.Sprintf("SELECT * FROM %s ORDER BY 1 DESC", table), nil
+	default:
+		return fmt.Sprintf("SELECT * FROM %s", table), nil
+	}
+}
+
+func tablesFromSchemaContext(schemaContext string) []string {
+	out := make([]string, 0)
+	for _, line := range strings.Split(schemaContext, "\n") {
+		if !strings.HasPrefix(line, "- ") {
+			continue
+		}
+		name := strings.TrimPrefix(line, "- ")
+		if idx := strings.Index(name, " "); idx >= 0 {
+			name = name[:idx]
+		}
+		if name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func (m *mockLLMClient) DescribeTable(ctx context.Context, tableDefinition string) (string, error) {
+	tables := tablesFromSchemaContext(tableDefinition)
+	if len(tables) == 0 {
+		return "Mock description of an unknown table.", nil
+	}
+	return fmt.Sprintf("Mock description of %s.", tables[0]), nil
+}
@@ -0,0 +1,474 @@
+package dbquery
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{in: "", want: 0},
+		{in: "abc", want: 1},
+		{in: "abcd", want: 1},
+		{in: "abcde", want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := estimateTokens(tt.in); got != tt.want {
+			t.Fatalf("estimateTokens(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+type stubLLMClient struct {
+	sql string
+	err error
+}
+
+func (s *stubLLMClient) GenerateSQL(ctx context.Context, schemaContext, naturalQuery string) (string, error) {
+	return s.sql, s.err
+}
+
+func TestMockLLMClient(t *testing.T) {
+	schemaContext := "Discovered schema:\n- orders (id INTEGER, total REAL)\n- users (id INTEGER, email TEXT)\n"
+	client := &mockLLMClient{}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "how many users are there", want: "SELECT COUNT(*) AS count FROM users"},
+		{query: "latest orders", want: "SELECT * FROM orders ORDER BY 1 DESC"},
+		{query: "show me every user", want: "SELECT * FROM users"},
+		{query: "anything", want: "SELECT * FROM orders"},
+	}
+
+	for _, tt := range tests {
+		got, err := client.GenerateSQL(context.Background(), schemaContext, tt.query)
+		if err != nil {
+			t.Fatalf("mock GenerateSQL returned error: %v", err)
+		}
+		if got != tt.want {
+			t.Fatalf("mock GenerateSQL(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestProcessNaturalLanguageQueryWithMockProvider(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`,
+		`INSERT INTO users (email) VALUES ('a@example.com'), ('b@example.com'), ('c@example.com')`,
+	)
+	cfg := Config{
+		Mode:            modeQuery,
+		DBType:          "sqlite",
+		Output:          "json",
+		Limit:           10,
+		SchemaMaxTables: 10,
+		Timeout:         5 * time.Second,
+		NoHistory:       true,
+		LLMProvider:     providerMock,
+	}
+
+	schemaContext, err := buildSchemaContext(context.Background(), db, cfg)
+	if err != nil {
+		t.Fatalf("buildSchemaContext returned error: %v", err)
+	}
+
+	entry, err := processNaturalLanguageQuery(context.Background(), db, cfg, schemaContext, "how many users", nil)
+	if err != nil {
+		t.Fatalf("processNaturalLanguageQuery returned error: %v", err)
+	}
+	if entry.SQL != "SELECT COUNT(*) AS count FROM users" || entry.Rows != 1 {
+		t.Fatalf("unexpected history entry: %+v", entry)
+	}
+
+	cfg.LLMClient = &stubLLMClient{sql: "   "}
+	_, err = processNaturalLanguageQuery(context.Background(), db, cfg, schemaContext, "anything", nil)
+	if !errors.Is(err, ErrEmptySQL) {
+		t.Fatalf("expected ErrEmptySQL from stub client, got %v", err)
+	}
+}
+
+func TestNewLLMHTTPClientUsesDedicatedTransport(t *testing.T) {
+	client, err := newLLMHTTPClient(Config{LLMHTTPTimeout: 7 * time.Second})
+	if err != nil {
+		t.Fatalf("newLLMHTTPClient returned error: %v", err)
+	}
+	if client == http.DefaultClient {
+		t.Fatal("expected a dedicated HTTP client")
+	}
+	if client.Timeout != 7*time.Second {
+		t.Fatalf("expected 7s timeout, got %s", client.Timeout)
+	}
+	if client.Transport == http.DefaultTransport {
+		t.Fatal("expected a dedicated transport")
+	}
+}
+
+func TestNewLLMClientReusesHTTPClientAcrossCalls(t *testing.T) {
+	cfg := Config{LLMHTTPTimeout: 5 * time.Second, llmHTTP: &sharedLLMHTTPClient{}}
+
+	first, err := newLLMClient(cfg)
+	if err != nil {
+		t.Fatalf("newLLMClient returned error: %v", err)
+	}
+	cfg.Model = "other-model"
+	second, err := newLLMClient(cfg)
+	if err != nil {
+		t.Fatalf("newLLMClient returned error: %v", err)
+	}
+	if first.(*openAIClient).http != second.(*openAIClient).http {
+		t.Fatal("expected both clients to share one HTTP client")
+	}
+}
+
+func TestNewLLMHTTPClientProxyAndCACert(t *testing.T) {
+	client, err := newLLMHTTPClient(Config{LLMProxy: "http://proxy.internal:3128"})
+	if err != nil {
+		t.Fatalf("newLLMHTTPClient with proxy returned error: %v", err)
+	}
+	transport := client.Transport.(*http.Transport)
+	req, _ := http.NewRequest(http.MethodPost, "https://api.openai.com/v1/chat/completions", nil)
+	proxyURL, err := transport.Proxy(req)
+	if err != nil || proxyURL == nil || proxyURL.Host != "proxy.internal:3128" {
+		t.Fatalf("expected explicit proxy, got %v (%v)", proxyURL, err)
+	}
+
+	if _, err := newLLMHTTPClient(Config{LLMProxy: "not a url"}); err == nil {
+		t.Fatal("expected error for invalid proxy URL")
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caPath, certPEM, 0o644); err != nil {
+		t.Fatalf("write CA fixture: %v", err)
+	}
+
+	client, err = newLLMHTTPClient(Config{LLMCACert: caPath, LLMHTTPTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("newLLMHTTPClient with CA returned error: %v", err)
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected TLS request to trust custom CA: %v", err)
+	}
+	resp.Body.Close()
+
+	badPath := filepath.Join(t.TempDir(), "bad.pem")
+	if err := os.WriteFile(badPath, []byte("not a cert"), 0o644); err != nil {
+		t.Fatalf("write bad CA fixture: %v", err)
+	}
+	if _, err := newLLMHTTPClient(Config{LLMCACert: badPath}); err == nil {
+		t.Fatal("expected error for CA file without certificates")
+	}
+}
+
+func TestOpenAIClientWritesDebugLog(t *testing.T) {
+	srv := newTestLLMServer(t, "SELECT 1")
+	cfg := DefaultConfig()
+	cfg.LLMBaseURL = srv.URL
+	cfg.APIKey = "sk-secret-key"
+	cfg.DBType = "sqlite"
+	cfg.Model = "gpt-4o-mini"
+	cfg.DebugLog = filepath.Join(t.TempDir(), "llm-debug.log")
+
+	client, err := newLLMClient(cfg)
+	if err != nil {
+		t.Fatalf("newLLMClient returned error: %v", err)
+	}
+	if _, err := client.GenerateSQL(context.Background(), "- users (id INTEGER)\n", "list users with key sk-secret-key"); err != nil {
+		t.Fatalf("GenerateSQL returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(cfg.DebugLog)
+	if err != nil {
+		t.Fatalf("read debug log: %v", err)
+	}
+	got := string(data)
+	for _, want := range []string{"--- system prompt", "You are a senior SQL engineer.", "- users (id INTEGER)", "--- request", `"model":"gpt-4o-mini"`, "--- response (status 200)", "SELECT 1"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected debug log to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "sk-secret-key") {
+		t.Fatalf("expected API key to be redacted, got:\n%s", got)
+	}
+}
+
+func TestProcessNaturalLanguageQueryRecordsFullPrompt(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`)
+	historyPath := filepath.Join(t.TempDir(), "history.jsonl")
+	cfg := Config{
+		Mode:              modeQuery,
+		DBType:            "sqlite",
+		Output:            "json",
+		Limit:             10,
+		Timeout:           5 * time.Second,
+		HistoryFile:       historyPath,
+		HistoryFullPrompt: true,
+		LLMProvider:       providerMock,
+	}
+	schemaContext := "- users (id INTEGER, email TEXT)\n"
+
+	if _, err := processNaturalLanguageQuery(context.Background(), db, cfg, schemaContext, "list users", nil); err != nil {
+		t.Fatalf("processNaturalLanguageQuery returned error: %v", err)
+	}
+
+	entries, err := readHistoryEntries(historyPath)
+	if err != nil {
+		t.Fatalf("readHistoryEntries returned error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].PromptFile == "" {
+		t.Fatalf("expected history entry with prompt file, got %+v", entries)
+	}
+
+	prompt, err := readHistoryPrompt(entries[0].PromptFile)
+	if err != nil {
+		t.Fatalf("readHistoryPrompt returned error: %v", err)
+	}
+	for _, want := range []string{"You are a senior SQL engineer.", "User request:\nlist users", schemaContext} {
+		if !strings.Contains(prompt, want) {
+			t.Fatalf("expected stored prompt to contain %q, got:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestOpenAIClientSendsCustomHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"SELECT 1"}}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.LLMBaseURL = srv.URL
+	cfg.APIKey = "k"
+	cfg.LLMHeaders = []string{"HTTP-Referer: https://example.com", "X-Title:dbquery"}
+	cfg.OpenAIOrg = "org-123"
+	cfg.OpenAIProject = "proj_456"
+
+	client, err := newLLMClient(cfg)
+	if err != nil {
+		t.Fatalf("newLLMClient returned error: %v", err)
+	}
+	if _, err := client.GenerateSQL(context.Background(), "", "anything"); err != nil {
+		t.Fatalf("GenerateSQL returned error: %v", err)
+	}
+	if got.Get("HTTP-Referer") != "https://example.com" || got.Get("X-Title") != "dbquery" || got.Get("Authorization") != "Bearer k" {
+		t.Fatalf("unexpected request headers: %v", got)
+	}
+	if got.Get("OpenAI-Organization") != "org-123" || got.Get("OpenAI-Project") != "proj_456" {
+		t.Fatalf("expected OpenAI org/project headers, got %v", got)
+	}
+
+	if _, _, err := parseLLMHeader("missing-colon"); err == nil {
+		t.Fatal("expected header without colon to be rejected")
+	}
+}
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "  SELECT 1  ", want: "SELECT 1"},
+		{name: "code fence", in: "```sql\nSELECT 1\n```", want: "SELECT 1"},
+		{name: "sql prefix", in: "SQL: SELECT 1", want: "SELECT 1"},
+		{name: "preamble", in: "Here is your query:\n\nSELECT id FROM users;", want: "SELECT id FROM users;"},
+		{name: "preamble and trailing prose", in: "Sure! Try this:\nSELECT id\nFROM users;\n\nThis returns every user id.", want: "SELECT id\nFROM users;"},
+		{name: "preamble with fence", in: "Here is the SQL:\n```sql\nSELECT 1\n```\nLet me know if you need more.", want: "SELECT 1"},
+		{name: "json sql field", in: `{"sql": "SELECT id FROM users"}`, want: "SELECT id FROM users"},
+		{name: "json query field", in: `{"query": "SELECT 1", "explanation": "one"}`, want: "SELECT 1"},
+		{name: "json fenced", in: "```json\n{\"sql\": \"SELECT 1\"}\n```", want: "SELECT 1"},
+		{name: "no sql", in: "I cannot answer that.", want: "I cannot answer that."},
+		{name: "empty", in: "   ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeSQL(tt.in); got != tt.want {
+				t.Fatalf("normalizeSQL(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIClientSendsTopPAndSeed(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"SELECT 1"}}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.LLMBaseURL = srv.URL
+	cfg.APIKey = "k"
+
+	seeded := cfg
+	seeded.TopP = 0.1
+	seed := int64(0)
+	seeded.Seed = &seed
+	for _, c := range []Config{cfg, seeded} {
+		client, err := newLLMClient(c)
+		if err != nil {
+			t.Fatalf("newLLMClient returned error: %v", err)
+		}
+		if _, err := client.GenerateSQL(context.Background(), "", "anything"); err != nil {
+			t.Fatalf("GenerateSQL returned error: %v", err)
+		}
+	}
+
+	if _, ok := bodies[0]["top_p"]; ok {
+		t.Fatalf("top_p should be omitted when unset: %v", bodies[0])
+	}
+	if _, ok := bodies[0]["seed"]; ok {
+		t.Fatalf("seed should be omitted when unset: %v", bodies[0])
+	}
+	if bodies[1]["top_p"] != 0.1 || bodies[1]["seed"] != float64(0) {
+		t.Fatalf("expected top_p and seed in request, got %v", bodies[1])
+	}
+}
+
+func TestOpenAIClientSendsChatTurnsAsMessages(t *testing.T) {
+	var body chatCompletionRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"SELECT 1"}}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.LLMBaseURL = srv.URL
+	cfg.APIKey = "k"
+	client, err := newLLMClient(cfg)
+	if err != nil {
+		t.Fatalf("newLLMClient returned error: %v", err)
+	}
+
+	turns := []chatTurn{{Query: "list users", SQL: "SELECT * FROM users"}}
+	if _, err := client.(chatTurnsGenerator).generateSQLWithTurns(context.Background(), "", "sort that by date instead", turns); err != nil {
+		t.Fatalf("GenerateSQL returned error: %v", err)
+	}
+
+	if len(body.Messages) != 4 || body.Messages[2].Role != "assistant" || body.Messages[2].Content != "SELECT * FROM users" {
+		t.Fatalf("expected earlier turn as user/assistant messages, got %+v", body.Messages)
+	}
+}
+
+func TestOpenAIClientStructuredOutput(t *testing.T) {
+	var formats []*responseFormat
+	rejectFormat := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body chatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		formats = append(formats, body.ResponseFormat)
+		switch {
+		case body.ResponseFormat != nil && rejectFormat:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Unrecognized request argument supplied: response_format"}}`))
+		case body.ResponseFormat != nil:
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"sql\":\"SELECT id FROM users\"}"}}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"SELECT 2"}}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.LLMBaseURL = srv.URL
+	cfg.APIKey = "k"
+	cfg.StructuredOutput = true
+	client, err := newLLMClient(cfg)
+	if err != nil {
+		t.Fatalf("newLLMClient returned error: %v", err)
+	}
+
+	got, err := client.GenerateSQL(context.Background(), "", "list users")
+	if err != nil || got != "SELECT id FROM users" {
+		t.Fatalf("expected SQL from the structured reply, got %q (%v)", got, err)
+	}
+	if len(formats) != 1 || formats[0] == nil || formats[0].JSONSchema.Name != "sql_query" {
+		t.Fatalf("expected a json_schema response_format, got %+v", formats)
+	}
+
+	rejectFormat = true
+	formats = nil
+	got, err = client.GenerateSQL(context.Background(), "", "list users")
+	if err != nil || got != "SELECT 2" {
+		t.Fatalf("expected plain-text fallback, got %q (%v)", got, err)
+	}
+	if len(formats) != 2 || formats[1] != nil {
+		t.Fatalf("expected a retry without response_format, got %+v", formats)
+	}
+}
+
+func TestAPIKeyWarning(t *testing.T) {
+	openAI := "https://api.openai.com/v1"
+	valid := "sk-" + strings.Repeat("a", 48)
+	tests := []struct {
+		name    string
+		key     string
+		baseURL string
+		want    string
+	}{
+		{name: "valid openai key", key: valid, baseURL: openAI},
+		{name: "project key", key: "sk-proj-" + strings.Repeat("b", 100), baseURL: openAI},
+		{name: "truncated", key: "sk-abc123", baseURL: openAI, want: "may be truncated"},
+		{name: "wrong prefix", key: strings.Repeat("c", 48), baseURL: openAI, want: `does not start with "sk-"`},
+		{name: "embedded whitespace", key: "sk-abc def", baseURL: "http://gateway.local/v1", want: "whitespace or quotes"},
+		{name: "custom gateway", key: "gw_123", baseURL: "http://gateway.local/v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := apiKeyWarning(tt.key, tt.baseURL)
+			if tt.want == "" && got != "" {
+				t.Fatalf("expected no warning, got %q", got)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Fatalf("expected warning containing %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGenerateSQLWithRetry(t *testing.T) {
+	llm := &scriptedLLMClient{replies: []string{"I cannot help with that.", "SELECT 1"}}
+	cfg := Config{RetryEmpty: true, LLMClient: llm}
+
+	var retried []string
+	got, err := generateSQLWithRetry(context.Background(), cfg, "", "count users", nil, func(reply string) { retried = append(retried, reply) })
+	if err != nil || got != "SELECT 1" {
+		t.Fatalf("generateSQLWithRetry = %q, %v", got, err)
+	}
+	if len(retried) != 1 || retried[0] != "I cannot help with that." || llm.queries[1] != retryEmptyQuery("count users") {
+		t.Fatalf("expected one retry with the stricter prompt, got retried=%q queries=%q", retried, llm.queries)
+	}
+
+	llm = &scriptedLLMClient{replies: []string{"no", "still no"}}
+	cfg.LLMClient = llm
+	got, err = generateSQLWithRetry(context.Background(), cfg, "", "count users", nil, nil)
+	if !errors.Is(err, ErrEmptySQL) || got != "still no" {
+		t.Fatalf("expected ErrEmptySQL with the unusable reply, got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,524 @@
+package dbquery
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testQueryArgs(t *testing.T, args ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	base := []string{
+		"--settings-file", filepath.Join(dir, "settings.json"),
+		"--profiles-file", filepath.Join(dir, "profiles.json"),
+		"--history-file", filepath.Join(dir, "history.jsonl"),
+	}
+	return append(base, args...)
+}
+
+func openTestSQLite(t *testing.T, statements ...string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(context.Background(), stmt); err != nil {
+			t.Fatalf("exec %q: %v", stmt, err)
+		}
+	}
+	return db
+}
+
+func TestParseQueryConfigRawSQL(t *testing.T) {
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-type", "sqlite", "--db-url", ":memory:", "--raw-sql", "SELECT 1"))
+	if err != nil {
+		t.Fatalf("raw-sql without API key should parse: %v", err)
+	}
+	if cfg.RawSQL != "SELECT 1" {
+		t.Fatalf("unexpected raw sql: %q", cfg.RawSQL)
+	}
+
+	_, err = parseQueryConfig(modeQuery, testQueryArgs(t, "--db-type", "sqlite", "--db-url", ":memory:", "--raw-sql", "SELECT 1", "--query", "count users", "--api-key", "k"))
+	if err == nil {
+		t.Fatal("expected error when --raw-sql and --query are combined")
+	}
+}
+
+func TestProcessRawSQL(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`,
+		`INSERT INTO users (email) VALUES ('a@example.com'), ('b@example.com')`,
+	)
+
+	historyPath := filepath.Join(t.TempDir(), "history.jsonl")
+	cfg := Config{
+		Mode:        modeQuery,
+		DBType:      "sqlite",
+		Output:      "json",
+		Limit:       10,
+		Timeout:     5 * time.Second,
+		HistoryFile: historyPath,
+	}
+
+	entry, err := processRawSQL(context.Background(), db, cfg, "SELECT email FROM users ORDER BY id")
+	if err != nil {
+		t.Fatalf("processRawSQL returned error: %v", err)
+	}
+	if entry.Rows != 2 {
+		t.Fatalf("expected 2 rows, got %d", entry.Rows)
+	}
+	if entry.SQL != "SELECT email FROM users ORDER BY id LIMIT 10;" {
+		t.Fatalf("unexpected executed SQL: %q", entry.SQL)
+	}
+
+	entries, err := readHistoryEntries(historyPath)
+	if err != nil {
+		t.Fatalf("readHistoryEntries returned error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].NaturalQuery != "" {
+		t.Fatalf("unexpected history entries: %+v", entries)
+	}
+
+	if _, err := processRawSQL(context.Background(), db, cfg, "DELETE FROM users"); err == nil {
+		t.Fatal("expected read-only guard to reject raw write SQL")
+	}
+}
+
+func TestResultSummary(t *testing.T) {
+	queryMs := int64(23)
+	if got := resultSummary(HistoryEntry{Rows: 10, DurationMs: 1500, QueryDurationMs: &queryMs}); got != "10 rows in 23ms" {
+		t.Fatalf("unexpected summary: %q", got)
+	}
+	if got := resultSummary(HistoryEntry{Rows: 1}); got != "1 row in 0ms" {
+		t.Fatalf("unexpected summary: %q", got)
+	}
+}
+
+func TestProcessRawSQLTruncateOutput(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`,
+		`INSERT INTO users (email) VALUES ('a@example.com'), ('b@example.com'), ('c@example.com')`,
+	)
+
+	outPath := filepath.Join(t.TempDir(), "out.json")
+	cfg := Config{
+		Mode:           modeQuery,
+		DBType:         "sqlite",
+		Output:         "json",
+		OutputFile:     outPath,
+		NoAutoLimit:    true,
+		TruncateOutput: 2,
+		Timeout:        5 * time.Second,
+		NoHistory:      true,
+	}
+
+	entry, err := processRawSQL(context.Background(), db, cfg, "SELECT email FROM users ORDER BY id")
+	if err != nil {
+		t.Fatalf("processRawSQL returned error: %v", err)
+	}
+	if entry.SQL != "SELECT email FROM users ORDER BY id" || entry.Rows != 3 {
+		t.Fatalf("expected unchanged SQL and all fetched rows recorded, got %q (%d rows)", entry.SQL, entry.Rows)
+	}
+
+	raw, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read output file: %v", err)
+	}
+	var emitted []map[string]any
+	if err := json.Unmarshal(raw, &emitted); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if len(emitted) != 2 || emitted[1]["email"] != "b@example.com" {
+		t.Fatalf("expected the first two rows to be emitted, got %v", emitted)
+	}
+
+	if _, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", "./app.db", "--raw-sql", "SELECT 1", "--truncate-output", "-1")); err == nil {
+		t.Fatal("expected negative --truncate-output to be rejected")
+	}
+}
+
+func TestParseQueryConfigLimitZeroDisablesAutoLimit(t *testing.T) {
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-type", "sqlite", "--db-url", ":memory:", "--raw-sql", "SELECT 1", "--limit", "0"))
+	if err != nil {
+		t.Fatalf("--limit 0 should parse: %v", err)
+	}
+	if cfg.Limit != 0 || !cfg.NoAutoLimit {
+		t.Fatalf("expected --limit 0 to imply --no-auto-limit, got limit=%d noAutoLimit=%v", cfg.Limit, cfg.NoAutoLimit)
+	}
+
+	if _, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-type", "sqlite", "--db-url", ":memory:", "--raw-sql", "SELECT 1", "--limit", "-1")); err == nil {
+		t.Fatal("expected negative --limit to be rejected")
+	}
+}
+
+func TestParseHistoryStatsConfig(t *testing.T) {
+	cfg, err := parseHistoryConfig([]string{"stats", "--limit", "5"})
+	if err != nil {
+		t.Fatalf("parseHistoryConfig returned error: %v", err)
+	}
+	if !cfg.HistoryStats || cfg.HistoryLimit != 5 {
+		t.Fatalf("unexpected config: stats=%v limit=%d", cfg.HistoryStats, cfg.HistoryLimit)
+	}
+
+	cfg, err = parseHistoryConfig([]string{"stats"})
+	if err != nil {
+		t.Fatalf("parseHistoryConfig returned error: %v", err)
+	}
+	if cfg.HistoryLimit != 0 {
+		t.Fatalf("expected stats without --limit to cover all entries, got limit=%d", cfg.HistoryLimit)
+	}
+	if cfg, _ := parseHistoryConfig(nil); cfg.HistoryLimit != 20 {
+		t.Fatalf("expected listings to keep the default limit, got %d", cfg.HistoryLimit)
+	}
+
+	if _, err := parseHistoryConfig([]string{"bogus"}); err == nil {
+		t.Fatal("expected error for unknown history argument")
+	}
+}
+
+func TestComputeHistoryStats(t *testing.T) {
+	ms := func(v int64) *int64 { return &v }
+	entries := []HistoryEntry{
+		{DurationMs: 300, LLMDurationMs: ms(200), QueryDurationMs: ms(60)},
+		{DurationMs: 100, QueryDurationMs: ms(30), Error: "boom"},
+		{DurationMs: 200, QueryDurationMs: ms(0)},
+	}
+	stats := computeHistoryStats(entries)
+	if stats.Entries != 3 || stats.Errors != 1 {
+		t.Fatalf("unexpected counts: %+v", stats)
+	}
+	if stats.LLMSamples != 1 || stats.QuerySamples != 3 {
+		t.Fatalf("expected 0 ms timings to count as samples: %+v", stats)
+	}
+	if stats.AvgDurationMs != 200 || stats.AvgLLMMs != 200 || stats.AvgQueryMs != 30 {
+		t.Fatalf("unexpected averages: %+v", stats)
+	}
+}
+
+func TestDedupHistoryEntries(t *testing.T) {
+	entries := []HistoryEntry{
+		{NaturalQuery: "count users", Rows: 1},
+		{NaturalQuery: "top customers", Rows: 10},
+		{NaturalQuery: "count  users ", Rows: 2},
+		{SQL: "SELECT 1"},
+		{SQL: "SELECT 1"},
+	}
+
+	got := dedupHistoryEntries(entries)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 distinct entries, got %d: %+v", len(got), got)
+	}
+	if got[0].NaturalQuery != "top customers" || got[1].Rows != 2 || got[2].SQL != "SELECT 1" {
+		t.Fatalf("expected most recent entries in chronological order, got %+v", got)
+	}
+}
+
+func TestRecordHistoryRedacted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	cfg := Config{Mode: modeQuery, HistoryFile: path, RedactHistory: true}
+	entry := HistoryEntry{Mode: modeQuery, DBType: "sqlite", NaturalQuery: "salary of  alice", SQL: "SELECT salary FROM staff WHERE name = 'alice'", Rows: 1, DurationMs: 12,
+		Error: `near "'alice'": syntax error`}
+	recordHistoryBestEffort(cfg, entry)
+	entry.NaturalQuery = "salary of alice"
+	recordHistoryBestEffort(cfg, entry)
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read history: %v", err)
+	}
+	if strings.Contains(string(raw), "alice") {
+		t.Fatalf("expected redacted history without query text, got %s", raw)
+	}
+
+	entries, err := readHistoryEntries(path)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 history entries, got %d (%v)", len(entries), err)
+	}
+	got := entries[0]
+	if got.NaturalQuery != "" || got.SQL != "" || !strings.HasPrefix(got.QueryHash, "hmac-sha256:") || !strings.HasPrefix(got.SQLHash, "hmac-sha256:") ||
+		!strings.HasPrefix(got.Error, "hmac-sha256:") || got.Rows != 1 || got.DurationMs != 12 {
+		t.Fatalf("unexpected redacted entry: %+v", got)
+	}
+	if historyQueryText(got) != got.QueryHash {
+		t.Fatalf("expected history to display the query hash, got %q", historyQueryText(got))
+	}
+	if len(dedupHistoryEntries(entries)) != 1 {
+		t.Fatal("expected whitespace-only differences to share a hash")
+	}
+
+	key, err := historyHashKey(path)
+	if err != nil || got.QueryHash != historyHash(key, "salary of alice") {
+		t.Fatalf("expected the hash to use the key stored next to history (%v)", err)
+	}
+	if historyHash([]byte("other key"), "salary of alice") == got.QueryHash {
+		t.Fatal("expected hashes to depend on the key")
+	}
+}
+
+type scriptedLLMClient struct {
+	replies []string
+	queries []string
+}
+
+func (c *scriptedLLMClient) GenerateSQL(_ context.Context, _, naturalQuery string) (string, error) {
+	c.queries = append(c.queries, naturalQuery)
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	return reply, nil
+}
+
+func TestProcessNaturalLanguageQueryRetryEmpty(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`)
+
+	historyPath := filepath.Join(t.TempDir(), "history.jsonl")
+	llm := &scriptedLLMClient{replies: []string{"Sorry, I cannot help with that.", "SELECT email FROM users"}}
+	cfg := Config{
+		Mode:        modeQuery,
+		DBType:      "sqlite",
+		Output:      "json",
+		Limit:       10,
+		Timeout:     5 * time.Second,
+		HistoryFile: historyPath,
+		RetryEmpty:  true,
+		LLMClient:   llm,
+	}
+
+	entry, err := processNaturalLanguageQuery(context.Background(), db, cfg, "", "all emails", nil)
+	if err != nil {
+		t.Fatalf("processNaturalLanguageQuery returned error: %v", err)
+	}
+	if entry.Attempt != 2 || entry.SQL != "SELECT email FROM users LIMIT 10;" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+	if len(llm.queries) != 2 || !strings.Contains(llm.queries[1], retryEmptyInstruction) {
+		t.Fatalf("expected stricter retry prompt, got %q", llm.queries)
+	}
+
+	entries, err := readHistoryEntries(historyPath)
+	if err != nil {
+		t.Fatalf("readHistoryEntries returned error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Attempt != 1 || entries[0].Error == "" || entries[1].Attempt != 2 {
+		t.Fatalf("expected both attempts in history, got %+v", entries)
+	}
+
+	cfg.RetryEmpty = false
+	cfg.LLMClient = &scriptedLLMClient{replies: []string{""}}
+	if _, err := processNaturalLanguageQuery(context.Background(), db, cfg, "", "all emails", nil); !errors.Is(err, ErrEmptySQL) {
+		t.Fatalf("expected ErrEmptySQL without --retry-empty, got %v", err)
+	}
+}
+
+func TestParseQueryConfigDetectsDBType(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--db-url", "./app.db", "--query", "x"}, want: "sqlite"},
+		{args: []string{"--db-type", "auto", "--db-url", "postgres://localhost/app", "--query", "x"}, want: "postgres"},
+		{args: []string{"--db-type", "mysql", "--db-url", "./app.db", "--query", "x"}, want: "mysql"},
+	}
+	for _, tt := range tests {
+		cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, append(tt.args, "--llm-provider", "mock")...))
+		if err != nil {
+			t.Fatalf("parseQueryConfig(%q) returned error: %v", tt.args, err)
+		}
+		if cfg.DBType != tt.want {
+			t.Fatalf("parseQueryConfig(%q) db type = %q, want %q", tt.args, cfg.DBType, tt.want)
+		}
+	}
+
+	_, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", "warehouse", "--query", "x", "--llm-provider", "mock"))
+	if err == nil || !strings.Contains(err.Error(), "unable to detect db type") {
+		t.Fatalf("expected detection error for undetectable --db-url, got %v", err)
+	}
+}
+
+func TestParseQueryConfigSeedAndTopP(t *testing.T) {
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", "./app.db", "--query", "x", "--llm-provider", "mock", "--seed", "42", "--top-p", "0.5"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if cfg.Seed == nil || *cfg.Seed != 42 || cfg.TopP != 0.5 {
+		t.Fatalf("unexpected seed/top_p: %v %v", cfg.Seed, cfg.TopP)
+	}
+
+	cfg, err = parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", "./app.db", "--query", "x", "--llm-provider", "mock"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if cfg.Seed != nil {
+		t.Fatalf("seed should be unset by default, got %d", *cfg.Seed)
+	}
+
+	if _, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", "./app.db", "--query", "x", "--llm-provider", "mock", "--top-p", "1.5")); err == nil {
+		t.Fatal("expected error for --top-p > 1")
+	}
+}
+
+func TestParseQueryConfigAllowWriteTables(t *testing.T) {
+	base := []string{"--db-url", "./app.db", "--raw-sql", "SELECT 1", "--allow-write-tables", "staging_foo, scratch_bar"}
+	if _, err := parseQueryConfig(modeQuery, testQueryArgs(t, base...)); err == nil || !strings.Contains(err.Error(), "requires --allow-write") {
+		t.Fatalf("expected --allow-write-tables without --allow-write to fail, got %v", err)
+	}
+
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, append(base, "--allow-write")...))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if strings.Join(cfg.AllowWriteTables, ",") != "staging_foo,scratch_bar" {
+		t.Fatalf("unexpected allow-write tables: %#v", cfg.AllowWriteTables)
+	}
+}
+
+func TestParseQueryConfigDumpPrompt(t *testing.T) {
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", "./app.db", "--query", "count users", "--dump-prompt"))
+	if err != nil {
+		t.Fatalf("--dump-prompt without API key should parse: %v", err)
+	}
+	if !cfg.DumpPrompt {
+		t.Fatal("expected DumpPrompt to be set")
+	}
+
+	if _, err := parseQueryConfig(modeChat, testQueryArgs(t, "--db-url", "./app.db", "--dump-prompt")); err == nil {
+		t.Fatal("expected --dump-prompt to be rejected in chat mode")
+	}
+	if _, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", "./app.db", "--raw-sql", "SELECT 1", "--dump-prompt")); err == nil {
+		t.Fatal("expected --dump-prompt to be rejected with --raw-sql")
+	}
+}
+
+func TestRunSingleQueryMultipleQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	_ = db.Close()
+
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", path, "--llm-provider", "mock", "--no-history", "--no-progress",
+		"--query", "count users", "--query", " ", "--query", "broken", "--query", "list users"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if strings.Join(cfg.NLQueries, "|") != "count users|broken|list users" || cfg.NLQuery != "count users" {
+		t.Fatalf("unexpected queries: %q (first %q)", cfg.NLQueries, cfg.NLQuery)
+	}
+
+	llm := &scriptedLLMClient{replies: []string{"SELECT count(*) FROM users", "SELECT * FROM no_such_table", "SELECT id FROM users"}}
+	cfg.LLMClient = llm
+	err = runSingleQuery(cfg)
+	if !errors.Is(err, ErrQuery) || err.Error() != "1 of 3 queries failed" {
+		t.Fatalf("expected one failed query reported as ErrQuery, got %v", err)
+	}
+	if len(llm.queries) != 3 {
+		t.Fatalf("expected every query to run after a failure, got %q", llm.queries)
+	}
+
+	if _, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", path, "--api-key", "k", "--query", "a", "--query", "b", "--output-file", "out.txt")); err == nil {
+		t.Fatal("expected --output-file with several queries to be rejected")
+	}
+}
+
+func TestReconnectSessionStartsFreshSession(t *testing.T) {
+	db := openTestSQLite(t)
+	cfg := Config{Timeout: 5 * time.Second, PreSQL: []string{"CREATE TEMP TABLE IF NOT EXISTS marker (n INTEGER)"}}
+	ctx := context.Background()
+
+	conn, err := openSession(ctx, db, cfg.PreSQL)
+	if err != nil {
+		t.Fatalf("openSession returned error: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, "CREATE TEMP TABLE scratch (n INTEGER)"); err != nil {
+		t.Fatalf("create temp table: %v", err)
+	}
+	if _, _, err := executeQuery(ctx, conn, "SELECT * FROM scratch", timeFormats{}); err != nil {
+		t.Fatalf("temp table should persist on the session: %v", err)
+	}
+
+	conn, err = reconnectSession(cfg, db, conn)
+	if err != nil {
+		t.Fatalf("reconnectSession returned error: %v", err)
+	}
+	defer conn.Close()
+	if _, _, err := executeQuery(ctx, conn, "SELECT * FROM scratch", timeFormats{}); err == nil {
+		t.Fatal("expected session state to be reset after reconnect")
+	}
+	if _, _, err := executeQuery(ctx, conn, "SELECT * FROM marker", timeFormats{}); err != nil {
+		t.Fatalf("expected --pre-sql to re-run on reconnect: %v", err)
+	}
+}
+
+func TestReconnectSessionRefusesInMemorySources(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE people (id INTEGER)`)
+	conn, err := openSession(context.Background(), db, nil)
+	if err != nil {
+		t.Fatalf("openSession returned error: %v", err)
+	}
+	defer conn.Close()
+
+	for _, cfg := range []Config{
+		{DBType: dbTypeCSV, DBURL: "people.csv", Timeout: 5 * time.Second},
+		{DBType: "sqlite", DBURL: ":memory:", Timeout: 5 * time.Second},
+		{DBType: "sqlite", DBURL: "file::memory:?cache=shared", Timeout: 5 * time.Second},
+	} {
+		if _, err := reconnectSession(cfg, db, conn); err == nil {
+			t.Fatalf("expected reconnect to be refused for %s %s", cfg.DBType, cfg.DBURL)
+		}
+	}
+	if _, _, err := executeQuery(context.Background(), conn, "SELECT * FROM people", timeFormats{}); err != nil {
+		t.Fatalf("the refused reconnect must keep the session and its tables: %v", err)
+	}
+}
+
+func TestAppendHistoryEntryConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	const writers, perWriter = 8, 25
+	big := strings.Repeat("x", 64*1024)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				entry := HistoryEntry{Mode: modeQuery, DBType: "sqlite", NaturalQuery: fmt.Sprintf("writer %d entry %d %s", w, i, big), Rows: i}
+				if err := appendHistoryEntry(path, entry); err != nil {
+					t.Errorf("appendHistoryEntry returned error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read history: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n")
+	if len(lines) != writers*perWriter {
+		t.Fatalf("expected %d history lines, got %d", writers*perWriter, len(lines))
+	}
+	for i, line := range lines {
+		var e HistoryEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %d is not a complete JSON entry: %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package dbquery
+
+import "strings"
+
+func applyColumnOptions(cfg Config, columns []string, rows []map[string]any) {
+	boolColumns(columns, rows, cfg.BoolColumns)
+	maskColumns(columns, rows, cfg.MaskColumns)
+}
+
+func maskColumns(columns []string, rows []map[string]any, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	var masked []string
+	for _, col := range columns {
+		for _, name := range names {
+			if strings.EqualFold(col, name) {
+				masked = append(masked, col)
+				break
+			}
+		}
+	}
+
+	for _, row := range rows {
+		for _, col := range masked {
+			if v, ok := row[col]; ok && v != nil {
+				row[col] = maskValue(formatCellValue(v))
+			}
+		}
+	}
+}
+
+// Only a short suffix stays visible, and never for short values, so masked
+// identifiers like SSNs cannot be reconstructed from the output.
+func maskValue(v string) string {
+	r := []rune(v)
+	keep := 0
+	switch {
+	case len(r) >= 12:
+		keep = 4
+	case len(r) > 6:
+		keep = 2
+	}
+	return strings.Repeat("*", len(r)-keep) + string(r[len(r)-keep:])
+}
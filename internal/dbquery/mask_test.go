@@ -0,0 +1,48 @@
+package dbquery
+
+import "testing"
+
+func TestMaskColumns(t *testing.T) {
+	columns := []string{"id", "Email", "ssn", "note"}
+	rows := []map[string]any{
+		{"id": int64(1), "Email": "alice@example.com", "ssn": "123-45-6789", "note": "ok"},
+		{"id": int64(2), "Email": nil, "ssn": int64(1234), "note": "ok"},
+	}
+
+	maskColumns(columns, rows, []string{"email", "ssn", "missing"})
+
+	if got := rows[0]["Email"]; got != "*************.com" {
+		t.Fatalf("unexpected masked email: %v", got)
+	}
+	if got := rows[0]["ssn"]; got != "*********89" {
+		t.Fatalf("unexpected masked ssn: %v", got)
+	}
+	if got := rows[1]["Email"]; got != nil {
+		t.Fatalf("NULL should stay NULL, got %v", got)
+	}
+	if got := rows[1]["ssn"]; got != "****" {
+		t.Fatalf("short values should be fully masked, got %v", got)
+	}
+	if rows[0]["id"] != int64(1) || rows[0]["note"] != "ok" {
+		t.Fatalf("unmasked columns changed: %+v", rows[0])
+	}
+}
+
+func TestMaskValue(t *testing.T) {
+	tests := map[string]string{
+		"":                 "",
+		"42":               "**",
+		"secret":           "******",
+		"1234567":          "*****67",
+		"123-45-6789":      "*********89",
+		"4111111111111111": "************1111",
+		"José":             "****",
+		"Zoë Müller":       "********er",
+		"東京都港区六本木一丁目":      "*********丁目",
+	}
+	for in, want := range tests {
+		if got := maskValue(in); got != want {
+			t.Errorf("maskValue(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
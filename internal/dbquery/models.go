@@ -0,0 +1,105 @@
+package dbquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+func runModels(cfg Config) error {
+	httpClient, err := newLLMHTTPClient(cfg)
+	if err != nil {
+		return wrapError(ErrConfig, err)
+	}
+	client := &openAIClient{cfg: cfg, http: httpClient}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.LLMHTTPTimeout)
+	defer cancel()
+
+	models, err := client.listModels(ctx)
+	if err != nil {
+		return wrapError(ErrLLM, err)
+	}
+
+	if cfg.Output == "json" {
+		payload, err := json.MarshalIndent(models, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode models: %w", err)
+		}
+		fmt.Println(string(payload))
+		return nil
+	}
+	for _, id := range models {
+		fmt.Println(id)
+	}
+	return nil
+}
+
+func (c *openAIClient) listModels(ctx context.Context) ([]string, error) {
+	endpoint := strings.TrimRight(c.cfg.LLMBaseURL, "/") + "/models"
+	respBody, status, err := c.send(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case status == http.StatusNotFound || status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented:
+		return nil, fmt.Errorf("provider at %s does not expose a models endpoint (status %d); check its documentation for model names", c.cfg.LLMBaseURL, status)
+	case status < 200 || status >= 300:
+		return nil, fmt.Errorf("models request failed with status %d: %s", status, strings.TrimSpace(string(respBody)))
+	}
+
+	models, err := parseModelList(respBody)
+	if err != nil {
+		return nil, err
+	}
+	if len(models) == 0 {
+		return nil, errors.New("provider returned no models")
+	}
+	return models, nil
+}
+
+func parseModelList(body []byte) ([]string, error) {
+	var decoded struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+		Models []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, fmt.Errorf("decode models response: %w", err)
+	}
+
+	seen := map[string]struct{}{}
+	ids := make([]string, 0, len(decoded.Data)+len(decoded.Models))
+	add := func(id string) {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return
+		}
+		if _, ok := seen[id]; ok {
+			return
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	for _, m := range decoded.Data {
+		add(m.ID)
+	}
+	for _, m := range decoded.Models {
+		if m.ID != "" {
+			add(m.ID)
+		} else {
+			add(m.Name)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
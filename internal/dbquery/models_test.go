@@ -0,0 +1,73 @@
+package dbquery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer k1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o-mini"},{"id":"gpt-4o"},{"id":"gpt-4o"}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := Config{APIKey: "k1", LLMBaseURL: srv.URL + "/v1/", LLMHTTPTimeout: 5 * time.Second}
+	client := &openAIClient{cfg: cfg, http: srv.Client()}
+	got, err := client.listModels(context.Background())
+	if err != nil {
+		t.Fatalf("listModels returned error: %v", err)
+	}
+	if want := []string{"gpt-4o", "gpt-4o-mini"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("listModels = %#v, want %#v", got, want)
+	}
+
+	client.cfg.LLMBaseURL = srv.URL + "/other"
+	if _, err := client.listModels(context.Background()); err == nil || !strings.Contains(err.Error(), "does not expose a models endpoint") {
+		t.Fatalf("expected a missing-endpoint error, got %v", err)
+	}
+}
+
+func TestParseModelList(t *testing.T) {
+	got, err := parseModelList([]byte(`{"models":[{"name":"llama3:8b"},{"id":"mistral"}]}`))
+	if err != nil {
+		t.Fatalf("parseModelList returned error: %v", err)
+	}
+	if want := []string{"llama3:8b", "mistral"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseModelList = %#v, want %#v", got, want)
+	}
+
+	if _, err := parseModelList([]byte(`<html>`)); err == nil {
+		t.Fatal("expected decode error for non-JSON body")
+	}
+}
+
+func TestParseModelsConfig(t *testing.T) {
+	dir := t.TempDir()
+	base := []string{"--settings-file", dir + "/config.json", "--profiles-file", dir + "/profiles.json"}
+
+	if _, err := parseConfig(append([]string{"models"}, base...)); !errors.Is(err, ErrMissingAPIKey) {
+		t.Fatalf("expected missing API key error, got %v", err)
+	}
+
+	cfg, err := parseConfig(append([]string{"models", "--api-key", "k1", "--output", "JSON"}, base...))
+	if err != nil {
+		t.Fatalf("parseConfig returned error: %v", err)
+	}
+	if cfg.Mode != modeModels || cfg.Output != "json" || cfg.APIKey != "k1" {
+		t.Fatalf("unexpected models config: %+v", cfg)
+	}
+}
@@ -0,0 +1,134 @@
+package dbquery
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func shouldGzipOutput(path string, force bool) bool {
+	return force || strings.HasSuffix(strings.ToLower(path), ".gz")
+}
+
+func outputDirFile(dir string, i int, format string, compress bool) string {
+	ext := "txt"
+	switch format {
+	case "json":
+		ext = "json"
+	case outputSQLInsert:
+		ext = "sql"
+	}
+	name := fmt.Sprintf("%03d.%s", i+1, ext)
+	if compress {
+		name += ".gz"
+	}
+	return filepath.Join(dir, name)
+}
+
+func outputFileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+func writeOutputFile(path string, data []byte, compress bool) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	var w io.Writer = tmp
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(tmp)
+		zw.Name = strings.TrimSuffix(filepath.Base(path), ".gz")
+		w = zw
+	}
+	if _, err := w.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func appendOutputFile(path string, data []byte, maxSize int64, maxFiles int) error {
+	if maxSize > 0 {
+		info, err := os.Stat(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err == nil && info.Size() > 0 && info.Size()+int64(len(data)) > maxSize {
+			if err := rotateOutputFile(path, maxFiles); err != nil {
+				return fmt.Errorf("rotate output file: %w", err)
+			}
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rotateOutputFile shifts path.1 to path.2 and so on, dropping the oldest copy beyond maxFiles.
+func rotateOutputFile(path string, maxFiles int) error {
+	if err := os.Remove(fmt.Sprintf("%s.%d", path, maxFiles)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(path, path+".1")
+}
+
+func parseByteSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	digits := strings.TrimRight(s, "KMGIB")
+	unit := int64(1)
+	switch strings.TrimSuffix(strings.TrimSuffix(s[len(digits):], "B"), "I") {
+	case "":
+	case "K":
+		unit = 1 << 10
+	case "M":
+		unit = 1 << 20
+	case "G":
+		unit = 1 << 30
+	default:
+		return 0, fmt.Errorf("unknown size unit in %q (expected B, KB, MB or GB)", value)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(digits), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q is not a positive size", value)
+	}
+	return n * unit, nil
+}
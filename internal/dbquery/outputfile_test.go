@@ -0,0 +1,172 @@
+package dbquery
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(`[{"id": 1}]`)
+
+	plain := filepath.Join(dir, "out.json")
+	if err := writeOutputFile(plain, data, shouldGzipOutput(plain, false)); err != nil {
+		t.Fatalf("writeOutputFile returned error: %v", err)
+	}
+	if got, _ := os.ReadFile(plain); string(got) != string(data) {
+		t.Fatalf("unexpected plain output: %q", got)
+	}
+
+	for _, tc := range []struct {
+		path  string
+		force bool
+	}{
+		{path: filepath.Join(dir, "out.json.GZ")},
+		{path: filepath.Join(dir, "forced.json"), force: true},
+	} {
+		if err := writeOutputFile(tc.path, data, shouldGzipOutput(tc.path, tc.force)); err != nil {
+			t.Fatalf("writeOutputFile(%s) returned error: %v", tc.path, err)
+		}
+		f, err := os.Open(tc.path)
+		if err != nil {
+			t.Fatalf("open %s: %v", tc.path, err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("%s is not gzip: %v", tc.path, err)
+		}
+		got, err := io.ReadAll(zr)
+		_ = f.Close()
+		if err != nil || string(got) != string(data) {
+			t.Fatalf("unexpected decompressed output %q (err %v)", got, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected no temp files left behind, got %d entries", len(entries))
+	}
+}
+
+func TestRunSingleQuerySkipIfExists(t *testing.T) {
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "report.json")
+	if err := os.WriteFile(outPath, []byte("[]"), 0o644); err != nil {
+		t.Fatalf("write existing output: %v", err)
+	}
+
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", filepath.Join(dir, "missing", "app.db"), "--llm-provider", "mock",
+		"--query", "list users", "--output-file", outPath, "--skip-if-exists"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	cfg.LLMClient = &describingLLMClient{}
+	if err := runSingleQuery(cfg); err != nil {
+		t.Fatalf("expected existing output file to skip all work, got %v", err)
+	}
+
+	if _, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", "./app.db", "--llm-provider", "mock", "--query", "list users", "--skip-if-exists")); err == nil {
+		t.Fatal("expected --skip-if-exists without --output-file to be rejected")
+	}
+}
+
+func TestRunSingleQueryOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY); INSERT INTO users (id) VALUES (1), (2)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	_ = db.Close()
+
+	outDir := filepath.Join(dir, "out")
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", dbPath, "--llm-provider", "mock", "--no-history", "--no-progress", "--output", "json",
+		"--query", "count users", "--query", "list users", "--output-dir", outDir))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if err := runSingleQuery(cfg); err != nil {
+		t.Fatalf("runSingleQuery returned error: %v", err)
+	}
+
+	for _, name := range []string{"001.json", "002.json"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Fatalf("expected %s to be written: %v", name, err)
+		}
+	}
+	if got := outputDirFile(outDir, 9, outputSQLInsert, true); got != filepath.Join(outDir, "010.sql.gz") {
+		t.Fatalf("unexpected output file name %q", got)
+	}
+
+	if _, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-url", dbPath, "--llm-provider", "mock", "--query", "a", "--output-dir", outDir, "--output-file", "x.json")); err == nil {
+		t.Fatal("expected --output-dir with --output-file to be rejected")
+	}
+}
+
+func TestAppendOutputFileRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.log")
+	for _, chunk := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n"} {
+		if err := appendOutputFile(path, []byte(chunk), 10, 2); err != nil {
+			t.Fatalf("appendOutputFile returned error: %v", err)
+		}
+	}
+
+	for name, want := range map[string]string{
+		path:        "eeee\n",
+		path + ".1": "cccc\ndddd\n",
+		path + ".2": "aaaa\nbbbb\n",
+	} {
+		if got, _ := os.ReadFile(name); string(got) != want {
+			t.Fatalf("%s = %q, want %q", filepath.Base(name), got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected no copies beyond --output-max-files, stat err %v", err)
+	}
+}
+
+func TestRunSQLQueryOutputAppendSeparatesResults(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE users (id INTEGER PRIMARY KEY)`, `INSERT INTO users (id) VALUES (1)`)
+	path := filepath.Join(t.TempDir(), "session.json")
+	cfg := Config{DBType: "sqlite", Output: "json", Timeout: 5 * time.Second, NoHistory: true, Quiet: true,
+		OutputFile: path, OutputAppend: true, stdout: io.Discard}
+
+	for i := 0; i < 2; i++ {
+		if _, err := processRawSQL(context.Background(), db, cfg, "SELECT id FROM users"); err != nil {
+			t.Fatalf("processRawSQL returned error: %v", err)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read appended output: %v", err)
+	}
+	if want := "[\n  {\n    \"id\": 1\n  }\n]\n"; string(got) != want+want {
+		t.Fatalf("expected each appended result on its own lines, got %q", got)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	for in, want := range map[string]int64{"512": 512, "10MB": 10 << 20, "4k": 4 << 10, "1GiB": 1 << 30, " 2 MB ": 2 << 20} {
+		got, err := parseByteSize(in)
+		if err != nil || got != want {
+			t.Fatalf("parseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MB", "10TB", "-1", "0"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Fatalf("parseByteSize(%q) should fail", in)
+		}
+	}
+}
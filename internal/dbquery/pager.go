@@ -0,0 +1,67 @@
+package dbquery
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const defaultPager = "less -FRX"
+
+func printResult(cfg Config, rendered string) {
+	if shouldPage(cfg, rendered, isTerminal(os.Stdout), terminalHeight()) {
+		err := runPager(pagerCommand(), rendered, os.Stdout)
+		if err == nil {
+			return
+		}
+		if cfg.Verbose {
+			fmt.Fprintf(os.Stderr, "warning: %v; printing directly\n", err)
+		}
+	}
+	fmt.Fprintln(cfg.out(), rendered)
+}
+
+func shouldPage(cfg Config, rendered string, tty bool, height int) bool {
+	if cfg.NoPager || cfg.stdout != nil || strings.TrimSpace(cfg.OutputFile) != "" || !tty {
+		return false
+	}
+	if cfg.Pager {
+		return true
+	}
+	// Leave room for the summary line and the next shell prompt.
+	return height > 0 && strings.Count(rendered, "\n")+1 > height-2
+}
+
+func pagerCommand() string {
+	if pager := strings.TrimSpace(os.Getenv("PAGER")); pager != "" {
+		return pager
+	}
+	return defaultPager
+}
+
+func terminalHeight() int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("LINES"))); err == nil && n > 0 {
+		return n
+	}
+	return ttyRows(os.Stdout)
+}
+
+func runPager(pager, text string, out io.Writer) error {
+	parts, err := splitCommandLine(pager)
+	if err != nil {
+		return fmt.Errorf("parse pager %q: %w", pager, err)
+	}
+	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd.Stdin = strings.NewReader(text + "\n")
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start pager %q: %w", pager, err)
+	}
+	// A non-zero exit usually means the user quit early; the output was already shown.
+	_ = cmd.Wait()
+	return nil
+}
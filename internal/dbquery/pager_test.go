@@ -0,0 +1,56 @@
+package dbquery
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestShouldPage(t *testing.T) {
+	tall := strings.Repeat("row\n", 40)
+	tests := []struct {
+		name   string
+		cfg    Config
+		text   string
+		tty    bool
+		height int
+		want   bool
+	}{
+		{name: "taller than terminal", text: tall, tty: true, height: 24, want: true},
+		{name: "fits", text: "a\nb", tty: true, height: 24, want: false},
+		{name: "redirected", text: tall, tty: false, height: 24, want: false},
+		{name: "unknown height", text: tall, tty: true, height: 0, want: false},
+		{name: "forced", cfg: Config{Pager: true}, text: "a", tty: true, height: 24, want: true},
+		{name: "forced but redirected", cfg: Config{Pager: true}, text: "a", tty: false, height: 24, want: false},
+		{name: "no-pager", cfg: Config{NoPager: true}, text: tall, tty: true, height: 24, want: false},
+		{name: "output file", cfg: Config{OutputFile: "out.txt"}, text: tall, tty: true, height: 24, want: false},
+	}
+	for _, tt := range tests {
+		if got := shouldPage(tt.cfg, tt.text, tt.tty, tt.height); got != tt.want {
+			t.Errorf("%s: shouldPage = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunPager(t *testing.T) {
+	var out bytes.Buffer
+	if err := runPager("cat", "line 1\nline 2", &out); err != nil {
+		t.Fatalf("runPager returned error: %v", err)
+	}
+	if out.String() != "line 1\nline 2\n" {
+		t.Fatalf("unexpected pager output: %q", out.String())
+	}
+	out.Reset()
+	if err := runPager(`sed "s/line/row/"`, "line 1", &out); err != nil {
+		t.Fatalf("runPager with a quoted argument returned error: %v", err)
+	}
+	if out.String() != "row 1\n" {
+		t.Fatalf("unexpected pager output with a quoted argument: %q", out.String())
+	}
+	if err := runPager(`less "+G`, "x", &out); err == nil {
+		t.Fatal("expected an error for an unparseable pager so the caller can fall back")
+	}
+	if err := runPager("dbquery-no-such-pager", "x", &out); err == nil {
+		t.Fatal("expected an error for a missing pager so the caller can fall back")
+	}
+}
@@ -0,0 +1,191 @@
+package dbquery
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+func postgresArrayElemType(typeName string) (string, bool) {
+	upper := strings.ToUpper(strings.TrimSpace(typeName))
+	if strings.HasPrefix(upper, "_") && len(upper) > 1 {
+		return upper[1:], true
+	}
+	if strings.HasSuffix(upper, "[]") {
+		return strings.TrimSuffix(upper, "[]"), true
+	}
+	return "", false
+}
+
+func postgresArrayValue(v any, elemType string) ([]any, bool) {
+	var s string
+	switch t := v.(type) {
+	case string:
+		s = t
+	case []byte:
+		s = string(t)
+	default:
+		return nil, false
+	}
+	arr, err := parsePostgresArray(s, elemType)
+	if err != nil {
+		return nil, false
+	}
+	return arr, true
+}
+
+func parsePostgresArray(s, elemType string) ([]any, error) {
+	s = strings.TrimSpace(s)
+	// Arrays with non-default bounds are prefixed with e.g. "[0:2]=".
+	if strings.HasPrefix(s, "[") {
+		if idx := strings.Index(s, "="); idx >= 0 {
+			s = s[idx+1:]
+		}
+	}
+	p := &pgArrayParser{s: s, elemType: elemType}
+	arr, err := p.parseArray()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.s) {
+		return nil, errors.New("trailing characters after array literal")
+	}
+	return arr, nil
+}
+
+type pgArrayParser struct {
+	s        string
+	pos      int
+	elemType string
+}
+
+func (p *pgArrayParser) parseArray() ([]any, error) {
+	if p.pos >= len(p.s) || p.s[p.pos] != '{' {
+		return nil, errors.New("array literal must start with '{'")
+	}
+	p.pos++
+
+	out := make([]any, 0)
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		return out, nil
+	}
+
+	for {
+		if p.pos >= len(p.s) {
+			return nil, errors.New("unterminated array literal")
+		}
+
+		var (
+			elem any
+			err  error
+		)
+		switch p.s[p.pos] {
+		case '{':
+			elem, err = p.parseArray()
+		case '"':
+			elem, err = p.parseQuoted()
+		default:
+			elem, err = p.parseUnquoted()
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, elem)
+
+		if p.pos >= len(p.s) {
+			return nil, errors.New("unterminated array literal")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return out, nil
+		default:
+			return nil, errors.New("unexpected character in array literal")
+		}
+	}
+}
+
+func (p *pgArrayParser) parseQuoted() (any, error) {
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch c {
+		case '\\':
+			if p.pos+1 >= len(p.s) {
+				return nil, errors.New("unterminated escape in array literal")
+			}
+			b.WriteByte(p.s[p.pos+1])
+			p.pos += 2
+		case '"':
+			p.pos++
+			return p.typedElem(b.String()), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return nil, errors.New("unterminated quoted array element")
+}
+
+func (p *pgArrayParser) parseUnquoted() (any, error) {
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != ',' && p.s[p.pos] != '}' {
+		p.pos++
+	}
+	raw := strings.TrimSpace(p.s[start:p.pos])
+	if strings.EqualFold(raw, "NULL") {
+		return nil, nil
+	}
+	return p.typedElem(raw), nil
+}
+
+func (p *pgArrayParser) typedElem(s string) any {
+	switch p.elemType {
+	case "INT2", "INT4", "INT8", "SMALLINT", "INTEGER", "BIGINT":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case "FLOAT4", "FLOAT8", "NUMERIC", "REAL", "DOUBLE PRECISION":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "BOOL", "BOOLEAN":
+		switch s {
+		case "t", "true":
+			return true
+		case "f", "false":
+			return false
+		}
+	}
+	return s
+}
+
+func formatArrayCell(arr []any) string {
+	parts := make([]string, len(arr))
+	for i, v := range arr {
+		parts[i] = formatCellValue(v)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func postgresArrayLiteral(arr []any) string {
+	parts := make([]string, len(arr))
+	for i, v := range arr {
+		switch t := v.(type) {
+		case nil:
+			parts[i] = "NULL"
+		case []any:
+			parts[i] = postgresArrayLiteral(t)
+		default:
+			s := formatCellValue(t)
+			s = strings.ReplaceAll(s, `\`, `\\`)
+			s = strings.ReplaceAll(s, `"`, `\"`)
+			parts[i] = `"` + s + `"`
+		}
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,60 @@
+package dbquery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePostgresArray(t *testing.T) {
+	tests := []struct {
+		in       string
+		elemType string
+		want     []any
+	}{
+		{in: "{a,b,c}", elemType: "TEXT", want: []any{"a", "b", "c"}},
+		{in: "{}", elemType: "TEXT", want: []any{}},
+		{in: `{"hello, world","say \"hi\"",NULL,"NULL"}`, elemType: "TEXT", want: []any{"hello, world", `say "hi"`, nil, "NULL"}},
+		{in: "{1,2,3}", elemType: "INT4", want: []any{int64(1), int64(2), int64(3)}},
+		{in: "{{1,2},{3,4}}", elemType: "INT8", want: []any{[]any{int64(1), int64(2)}, []any{int64(3), int64(4)}}},
+		{in: "{t,f}", elemType: "BOOL", want: []any{true, false}},
+		{in: "[0:1]={1.5,2}", elemType: "FLOAT8", want: []any{1.5, float64(2)}},
+	}
+	for _, tt := range tests {
+		got, err := parsePostgresArray(tt.in, tt.elemType)
+		if err != nil {
+			t.Fatalf("parsePostgresArray(%q) returned error: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("parsePostgresArray(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"a,b", "{a,b", `{"a}`} {
+		if _, err := parsePostgresArray(bad, "TEXT"); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestNormalizeColumnValuePostgresArray(t *testing.T) {
+	v := normalizeColumnValue("{a,NULL,c}", "_TEXT", timeFormats{})
+	rows := []map[string]any{{"tags": v}}
+
+	out, err := renderOutput("json", []string{"tags"}, rows, renderOptions{})
+	if err != nil {
+		t.Fatalf("renderOutput returned error: %v", err)
+	}
+	if !strings.Contains(strings.Join(strings.Fields(out), ""), `"tags":["a",null,"c"]`) {
+		t.Fatalf("expected JSON array, got:\n%s", out)
+	}
+
+	table := renderTable([]string{"tags"}, rows, renderOptions{})
+	if !strings.Contains(table, "[a, NULL, c]") {
+		t.Fatalf("expected readable array in table, got:\n%s", table)
+	}
+
+	if got := normalizeColumnValue("{a,b}", "TEXT", timeFormats{}); got != "{a,b}" {
+		t.Fatalf("non-array columns should be left alone, got %#v", got)
+	}
+}
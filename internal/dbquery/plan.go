@@ -0,0 +1,97 @@
+package dbquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func checkPlanCost(ctx context.Context, db DBTX, dbType, query string, maxCost float64) error {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+
+	var (
+		cost float64
+		unit string
+		err  error
+	)
+	switch dbType {
+	case "postgres":
+		cost, err = postgresPlanCost(ctx, db, query)
+		unit = "total cost"
+	case "mysql":
+		cost, err = mysqlPlanRows(ctx, db, query)
+		unit = "rows examined"
+	default:
+		return fmt.Errorf("plan cost checks are not supported for %s", dbType)
+	}
+	if err != nil {
+		return fmt.Errorf("explain query: %w", err)
+	}
+
+	if cost > maxCost {
+		return wrapError(ErrPlanCostExceeded, fmt.Errorf(
+			"query plan estimate (%s %.0f) exceeds --max-plan-cost %.0f; refine the query or pass --force to run it anyway",
+			unit, cost, maxCost,
+		))
+	}
+	return nil
+}
+
+func postgresPlanCost(ctx context.Context, db DBTX, query string) (float64, error) {
+	columns, rows, err := executeQuery(ctx, db, "EXPLAIN (FORMAT JSON) "+query, timeFormats{})
+	if err != nil {
+		return 0, err
+	}
+	if len(columns) == 0 || len(rows) == 0 {
+		return 0, errors.New("empty plan")
+	}
+	return parsePostgresPlanCost([]byte(formatCellValue(rows[0][columns[0]])))
+}
+
+func parsePostgresPlanCost(raw []byte) (float64, error) {
+	var plans []struct {
+		Plan struct {
+			TotalCost *float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil {
+		return 0, fmt.Errorf("parse plan: %w", err)
+	}
+	if len(plans) == 0 || plans[0].Plan.TotalCost == nil {
+		return 0, errors.New("plan has no total cost")
+	}
+	return *plans[0].Plan.TotalCost, nil
+}
+
+func mysqlPlanRows(ctx context.Context, db DBTX, query string) (float64, error) {
+	columns, rows, err := executeQuery(ctx, db, "EXPLAIN "+query, timeFormats{})
+	if err != nil {
+		return 0, err
+	}
+	return estimateMySQLPlanRows(columns, rows)
+}
+
+func estimateMySQLPlanRows(columns []string, rows []map[string]any) (float64, error) {
+	rowsColumn := ""
+	for _, c := range columns {
+		if strings.EqualFold(c, "rows") {
+			rowsColumn = c
+		}
+	}
+	if rowsColumn == "" {
+		return 0, errors.New("plan has no rows column")
+	}
+
+	// Nested-loop joins examine the product of the per-table estimates.
+	total := 1.0
+	for _, row := range rows {
+		n, err := strconv.ParseFloat(formatCellValue(row[rowsColumn]), 64)
+		if err == nil && n > 0 {
+			total *= n
+		}
+	}
+	return total, nil
+}
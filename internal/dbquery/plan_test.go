@@ -0,0 +1,72 @@
+package dbquery
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParsePostgresPlanCost(t *testing.T) {
+	raw := []byte(`[{"Plan": {"Node Type": "Seq Scan", "Startup Cost": 0.00, "Total Cost": 18334.50, "Plan Rows": 1000000}}]`)
+	cost, err := parsePostgresPlanCost(raw)
+	if err != nil {
+		t.Fatalf("parsePostgresPlanCost returned error: %v", err)
+	}
+	if cost != 18334.50 {
+		t.Fatalf("expected total cost 18334.50, got %v", cost)
+	}
+
+	if _, err := parsePostgresPlanCost([]byte(`[{"Plan": {}}]`)); err == nil {
+		t.Fatal("expected error for plan without total cost")
+	}
+}
+
+func TestEstimateMySQLPlanRows(t *testing.T) {
+	columns := []string{"id", "select_type", "table", "rows"}
+	rows := []map[string]any{
+		{"id": 1, "select_type": "SIMPLE", "table": "orders", "rows": "2000"},
+		{"id": 1, "select_type": "SIMPLE", "table": "users", "rows": int64(5)},
+		{"id": 1, "select_type": "SIMPLE", "table": nil, "rows": nil},
+	}
+	total, err := estimateMySQLPlanRows(columns, rows)
+	if err != nil {
+		t.Fatalf("estimateMySQLPlanRows returned error: %v", err)
+	}
+	if total != 10000 {
+		t.Fatalf("expected 10000 rows examined, got %v", total)
+	}
+
+	if _, err := estimateMySQLPlanRows([]string{"id"}, nil); err == nil {
+		t.Fatal("expected error when plan has no rows column")
+	}
+}
+
+func TestParseQueryConfigMaxPlanCost(t *testing.T) {
+	if _, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-type", "sqlite", "--db-url", ":memory:", "--raw-sql", "SELECT 1", "--max-plan-cost", "1000")); err == nil {
+		t.Fatal("expected --max-plan-cost to be rejected for sqlite")
+	}
+
+	cfg, err := parseQueryConfig(modeQuery, testQueryArgs(t, "--db-type", "postgres", "--db-url", "postgres://localhost/app", "--raw-sql", "SELECT 1", "--max-plan-cost", "1000", "--force"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if cfg.MaxPlanCost != 1000 || !cfg.Force {
+		t.Fatalf("unexpected plan cost config: %+v", cfg)
+	}
+}
+
+func TestCheckQueryGuardsPlanCost(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE events (id INTEGER PRIMARY KEY)`)
+	cfg := Config{DBType: "postgres", MaxPlanCost: 10}
+
+	err := checkQueryGuards(context.Background(), db, cfg, "SELECT * FROM events", "SELECT * FROM events", false)
+	if !errors.Is(err, ErrQuery) || !strings.Contains(err.Error(), "explain query") {
+		t.Fatalf("expected the plan cost check to run, got %v", err)
+	}
+
+	cfg.Force = true
+	if err := checkQueryGuards(context.Background(), db, cfg, "SELECT * FROM events", "SELECT * FROM events", false); err != nil {
+		t.Fatalf("expected --force to skip the plan cost check, got %v", err)
+	}
+}
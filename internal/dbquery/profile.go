@@ -11,41 +11,87 @@ import (
 )
 
 type Profile struct {
+	Inherit         string   `json:"inherit,omitempty"`
 	DBType          string   `json:"db_type,omitempty"`
 	DBURL           string   `json:"db_url,omitempty"`
+	ReadURL         string   `json:"read_url,omitempty"`
+	DBVersion       string   `json:"db_version,omitempty"`
 	Output          string   `json:"output,omitempty"`
+	TableStyle      string   `json:"table_style,omitempty"`
+	NumberFormat    string   `json:"number_format,omitempty"`
+	HeaderCase      string   `json:"header_case,omitempty"`
+	DateFormat      string   `json:"date_format,omitempty"`
+	DateTimeFormat  string   `json:"datetime_format,omitempty"`
+	TimeFormat      string   `json:"time_format,omitempty"`
 	Limit           int      `json:"limit,omitempty"`
 	Tables          []string `json:"tables,omitempty"`
+	PreSQL          []string `json:"pre_sql,omitempty"`
 	SchemaFile      string   `json:"schema_file,omitempty"`
+	ExamplesFile    string   `json:"examples_file,omitempty"`
 	SchemaMaxTables int      `json:"schema_max_tables,omitempty"`
+	SchemaMaxTokens int      `json:"schema_max_tokens,omitempty"`
+	NoViews         bool     `json:"no_views,omitempty"`
 
-	Model       string  `json:"model,omitempty"`
-	LLMBaseURL  string  `json:"llm_base_url,omitempty"`
-	Temperature float64 `json:"temperature,omitempty"`
-	MaxTokens   int     `json:"max_tokens,omitempty"`
-	Timeout     string  `json:"timeout,omitempty"`
+	Model          string   `json:"model,omitempty"`
+	LLMBaseURL     string   `json:"llm_base_url,omitempty"`
+	LLMProvider    string   `json:"llm_provider,omitempty"`
+	LLMProxy       string   `json:"llm_proxy,omitempty"`
+	LLMCACert      string   `json:"llm_ca_cert,omitempty"`
+	LLMHeaders     []string `json:"llm_headers,omitempty"`
+	Temperature    float64  `json:"temperature,omitempty"`
+	MaxTokens      int      `json:"max_tokens,omitempty"`
+	TopP           float64  `json:"top_p,omitempty"`
+	Seed           *int64   `json:"seed,omitempty"`
+	Timeout        string   `json:"timeout,omitempty"`
+	LLMHTTPTimeout string   `json:"llm_http_timeout,omitempty"`
 
-	AllowWrite  bool `json:"allow_write,omitempty"`
-	NoAutoLimit bool `json:"no_auto_limit,omitempty"`
+	AllowWrite       bool     `json:"allow_write,omitempty"`
+	AllowWriteTables []string `json:"allow_write_tables,omitempty"`
+	DenyKeywords     []string `json:"deny_keywords,omitempty"`
+	AllowKeywords    []string `json:"allow_keywords,omitempty"`
+	NoAutoLimit      bool     `json:"no_auto_limit,omitempty"`
+
+	HistoryFullPrompt bool    `json:"history_full_prompt,omitempty"`
+	RedactHistory     bool    `json:"redact_history,omitempty"`
+	SchemaCacheMaxAge string  `json:"schema_cache_max_age,omitempty"`
+	SchemaConstraints bool    `json:"schema_constraints,omitempty"`
+	QuoteIdentifiers  bool    `json:"quote_identifiers,omitempty"`
+	SystemSchemas     bool    `json:"include_system_schemas,omitempty"`
+	MaxPlanCost       float64 `json:"max_plan_cost,omitempty"`
+	RequireWhere      int64   `json:"require_where,omitempty"`
 }
 
-func loadProfile(path, name string) (Profile, error) {
+func loadProfileChain(path, name string) ([]Profile, error) {
 	profiles, err := loadProfiles(path)
 	if err != nil {
-		return Profile{}, err
+		return nil, err
 	}
 
 	key := strings.TrimSpace(name)
 	if key == "" {
-		return Profile{}, errors.New("profile name cannot be empty")
+		return nil, errors.New("profile name cannot be empty")
 	}
 
-	p, ok := profiles[key]
-	if !ok {
-		return Profile{}, fmt.Errorf("profile %q not found in %s", key, path)
+	var chain []Profile
+	var seen []string
+	for key != "" {
+		for _, name := range seen {
+			if name == key {
+				return nil, fmt.Errorf("profile inheritance cycle: %s -> %s", strings.Join(seen, " -> "), key)
+			}
+		}
+		p, ok := profiles[key]
+		if !ok {
+			if len(seen) > 0 {
+				return nil, fmt.Errorf("profile %q inherits from %q, which is not in %s", seen[len(seen)-1], key, path)
+			}
+			return nil, fmt.Errorf("profile %q not found in %s", key, path)
+		}
+		seen = append(seen, key)
+		chain = append([]Profile{p}, chain...)
+		key = strings.TrimSpace(p.Inherit)
 	}
-
-	return p, nil
+	return chain, nil
 }
 
 func loadProfiles(path string) (map[string]Profile, error) {
@@ -81,7 +127,10 @@ func saveProfile(path, name string, cfg Config) error {
 	}
 
 	profiles[key] = profileFromConfig(cfg)
+	return writeProfiles(path, profiles)
+}
 
+func writeProfiles(path string, profiles map[string]Profile) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("create profiles directory: %w", err)
 	}
@@ -101,18 +150,49 @@ func profileFromConfig(cfg Config) Profile {
 	return Profile{
 		DBType:          cfg.DBType,
 		DBURL:           cfg.DBURL,
+		ReadURL:         cfg.ReadURL,
+		DBVersion:       cfg.DBVersion,
 		Output:          cfg.Output,
+		TableStyle:      cfg.TableStyle,
+		NumberFormat:    cfg.NumberFormat,
+		HeaderCase:      cfg.HeaderCase,
+		DateFormat:      cfg.DateFormat,
+		DateTimeFormat:  cfg.DateTimeFormat,
+		TimeFormat:      cfg.TimeFormat,
 		Limit:           cfg.Limit,
 		Tables:          append([]string(nil), cfg.Tables...),
+		PreSQL:          append([]string(nil), cfg.PreSQL...),
 		SchemaFile:      cfg.SchemaFile,
+		ExamplesFile:    cfg.ExamplesFile,
 		SchemaMaxTables: cfg.SchemaMaxTables,
+		SchemaMaxTokens: cfg.SchemaMaxTokens,
+		NoViews:         cfg.NoViews,
 		Model:           cfg.Model,
 		LLMBaseURL:      cfg.LLMBaseURL,
+		LLMProvider:     cfg.LLMProvider,
+		LLMProxy:        cfg.LLMProxy,
+		LLMCACert:       cfg.LLMCACert,
+		LLMHeaders:      append([]string(nil), cfg.LLMHeaders...),
 		Temperature:     cfg.Temperature,
 		MaxTokens:       cfg.MaxTokens,
+		TopP:            cfg.TopP,
+		Seed:            cfg.Seed,
 		Timeout:         cfg.Timeout.String(),
+		LLMHTTPTimeout:  cfg.LLMHTTPTimeout.String(),
 		AllowWrite:      cfg.AllowWrite,
 		NoAutoLimit:     cfg.NoAutoLimit,
+
+		HistoryFullPrompt: cfg.HistoryFullPrompt,
+		RedactHistory:     cfg.RedactHistory,
+		SchemaCacheMaxAge: cfg.SchemaCacheMaxAge.String(),
+		SchemaConstraints: cfg.SchemaConstraints,
+		SystemSchemas:     cfg.SystemSchemas,
+		QuoteIdentifiers:  cfg.QuoteIdentifiers,
+		MaxPlanCost:       cfg.MaxPlanCost,
+		RequireWhere:      cfg.RequireWhere,
+		AllowWriteTables:  append([]string(nil), cfg.AllowWriteTables...),
+		DenyKeywords:      append([]string(nil), cfg.DenyKeywords...),
+		AllowKeywords:     append([]string(nil), cfg.AllowKeywords...),
 	}
 }
 
@@ -121,23 +201,69 @@ func applyProfileDefaults(cfg *Config, p Profile) {
 		cfg.DBType = strings.TrimSpace(p.DBType)
 	}
 	if strings.TrimSpace(p.DBURL) != "" {
+		// A read replica belongs to its primary, so a new db_url drops an inherited read_url.
 		cfg.DBURL = strings.TrimSpace(p.DBURL)
+		cfg.ReadURL = strings.TrimSpace(p.ReadURL)
+	} else if strings.TrimSpace(p.ReadURL) != "" {
+		cfg.ReadURL = strings.TrimSpace(p.ReadURL)
+	}
+	if strings.TrimSpace(p.DBVersion) != "" {
+		cfg.DBVersion = strings.TrimSpace(p.DBVersion)
 	}
 	if strings.TrimSpace(p.Output) != "" {
 		cfg.Output = strings.TrimSpace(p.Output)
 	}
+	if strings.TrimSpace(p.TableStyle) != "" {
+		cfg.TableStyle = strings.TrimSpace(p.TableStyle)
+	}
+	if strings.TrimSpace(p.NumberFormat) != "" {
+		cfg.NumberFormat = strings.TrimSpace(p.NumberFormat)
+	}
+	if strings.TrimSpace(p.HeaderCase) != "" {
+		cfg.HeaderCase = strings.TrimSpace(p.HeaderCase)
+	}
+	if p.DateFormat != "" {
+		cfg.DateFormat = p.DateFormat
+	}
+	if p.DateTimeFormat != "" {
+		cfg.DateTimeFormat = p.DateTimeFormat
+	}
+	if p.TimeFormat != "" {
+		cfg.TimeFormat = p.TimeFormat
+	}
 	if p.Limit > 0 {
 		cfg.Limit = p.Limit
 	}
 	if len(p.Tables) > 0 {
 		cfg.Tables = append([]string(nil), p.Tables...)
 	}
+	if len(p.PreSQL) > 0 {
+		cfg.PreSQL = append([]string(nil), p.PreSQL...)
+	}
 	if strings.TrimSpace(p.SchemaFile) != "" {
 		cfg.SchemaFile = strings.TrimSpace(p.SchemaFile)
 	}
+	if strings.TrimSpace(p.ExamplesFile) != "" {
+		cfg.ExamplesFile = strings.TrimSpace(p.ExamplesFile)
+	}
 	if p.SchemaMaxTables > 0 {
 		cfg.SchemaMaxTables = p.SchemaMaxTables
 	}
+	if p.SchemaMaxTokens > 0 {
+		cfg.SchemaMaxTokens = p.SchemaMaxTokens
+	}
+	if p.NoViews {
+		cfg.NoViews = true
+	}
+	if p.SchemaConstraints {
+		cfg.SchemaConstraints = true
+	}
+	if p.SystemSchemas {
+		cfg.SystemSchemas = true
+	}
+	if p.QuoteIdentifiers {
+		cfg.QuoteIdentifiers = true
+	}
 
 	if strings.TrimSpace(p.Model) != "" {
 		cfg.Model = strings.TrimSpace(p.Model)
@@ -145,17 +271,75 @@ func applyProfileDefaults(cfg *Config, p Profile) {
 	if strings.TrimSpace(p.LLMBaseURL) != "" {
 		cfg.LLMBaseURL = strings.TrimSpace(p.LLMBaseURL)
 	}
+	if strings.TrimSpace(p.LLMProvider) != "" {
+		cfg.LLMProvider = strings.TrimSpace(p.LLMProvider)
+	}
+	if strings.TrimSpace(p.LLMProxy) != "" {
+		cfg.LLMProxy = strings.TrimSpace(p.LLMProxy)
+	}
+	if strings.TrimSpace(p.LLMCACert) != "" {
+		cfg.LLMCACert = strings.TrimSpace(p.LLMCACert)
+	}
+	if len(p.LLMHeaders) > 0 {
+		cfg.LLMHeaders = append([]string(nil), p.LLMHeaders...)
+	}
 	if p.MaxTokens > 0 {
 		cfg.MaxTokens = p.MaxTokens
 	}
+	if p.TopP > 0 {
+		cfg.TopP = p.TopP
+	}
+	if p.Seed != nil {
+		seed := *p.Seed
+		cfg.Seed = &seed
+	}
 	if strings.TrimSpace(p.Timeout) != "" {
 		d, err := time.ParseDuration(strings.TrimSpace(p.Timeout))
 		if err == nil && d > 0 {
 			cfg.Timeout = d
 		}
 	}
-	cfg.Temperature = p.Temperature
+	if strings.TrimSpace(p.LLMHTTPTimeout) != "" {
+		d, err := time.ParseDuration(strings.TrimSpace(p.LLMHTTPTimeout))
+		if err == nil && d > 0 {
+			cfg.LLMHTTPTimeout = d
+		}
+	}
+	if strings.TrimSpace(p.SchemaCacheMaxAge) != "" {
+		d, err := time.ParseDuration(strings.TrimSpace(p.SchemaCacheMaxAge))
+		if err == nil && d > 0 {
+			cfg.SchemaCacheMaxAge = d
+		}
+	}
+	if p.Temperature != 0 {
+		cfg.Temperature = p.Temperature
+	}
 
-	cfg.AllowWrite = p.AllowWrite
-	cfg.NoAutoLimit = p.NoAutoLimit
+	if p.AllowWrite {
+		cfg.AllowWrite = true
+	}
+	if len(p.AllowWriteTables) > 0 {
+		cfg.AllowWriteTables = append([]string(nil), p.AllowWriteTables...)
+	}
+	if len(p.DenyKeywords) > 0 {
+		cfg.DenyKeywords = append([]string(nil), p.DenyKeywords...)
+	}
+	if len(p.AllowKeywords) > 0 {
+		cfg.AllowKeywords = append([]string(nil), p.AllowKeywords...)
+	}
+	if p.NoAutoLimit {
+		cfg.NoAutoLimit = true
+	}
+	if p.HistoryFullPrompt {
+		cfg.HistoryFullPrompt = true
+	}
+	if p.RedactHistory {
+		cfg.RedactHistory = true
+	}
+	if p.MaxPlanCost > 0 {
+		cfg.MaxPlanCost = p.MaxPlanCost
+	}
+	if p.RequireWhere > 0 {
+		cfg.RequireWhere = p.RequireWhere
+	}
 }
@@ -0,0 +1,274 @@
+package dbquery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	profileActionExport = "export"
+	profileActionImport = "import"
+)
+
+var keywordDSNPasswordPattern = regexp.MustCompile(`(?i)(^|\s)(?:ssl)?password=('[^']*'|\S*)`)
+
+func runProfile(cfg Config) error {
+	switch cfg.ProfileAction {
+	case profileActionExport:
+		return runProfileExport(cfg)
+	case profileActionImport:
+		return runProfileImport(cfg)
+	default:
+		return fmt.Errorf("unsupported profile action %q (expected export|import)", cfg.ProfileAction)
+	}
+}
+
+func runProfileExport(cfg Config) error {
+	profiles, err := loadProfiles(cfg.ProfilesFile)
+	if err != nil {
+		return err
+	}
+
+	names := cfg.ProfileArgs
+	if len(names) == 0 {
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	bundle := make(map[string]Profile, len(names))
+	for _, name := range names {
+		p, ok := profiles[name]
+		if !ok {
+			return fmt.Errorf("profile %q not found in %s", name, cfg.ProfilesFile)
+		}
+		shared, stripped := shareableProfile(p)
+		for _, field := range stripped {
+			fmt.Fprintf(os.Stderr, "note: stripped %s from profile %q\n", field, name)
+		}
+		bundle[name] = shared
+	}
+
+	payload, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode profiles: %w", err)
+	}
+
+	if cfg.ProfileBundle == "" {
+		fmt.Println(string(payload))
+		return nil
+	}
+	if err := os.WriteFile(cfg.ProfileBundle, payload, 0o644); err != nil {
+		return fmt.Errorf("write profiles bundle: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d %s to %s\n", len(bundle), pluralize(len(bundle), "profile", "profiles"), cfg.ProfileBundle)
+	return nil
+}
+
+func runProfileImport(cfg Config) error {
+	if _, err := os.Stat(cfg.ProfileBundle); err != nil {
+		return fmt.Errorf("read profiles bundle: %w", err)
+	}
+	bundle, err := loadProfiles(cfg.ProfileBundle)
+	if err != nil {
+		return err
+	}
+	profiles, err := loadProfiles(cfg.ProfilesFile)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(bundle))
+	for name := range bundle {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var added, updated, unchanged, conflicts []string
+	for _, name := range names {
+		existing, ok := profiles[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case reflect.DeepEqual(existing, bundle[name]):
+			unchanged = append(unchanged, name)
+		case cfg.ProfileOverwrite:
+			updated = append(updated, name)
+		default:
+			conflicts = append(conflicts, name)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("profiles already exist with different settings: %s (use --overwrite to replace them)", strings.Join(conflicts, ", "))
+	}
+
+	for _, name := range append(added, updated...) {
+		profiles[name] = bundle[name]
+	}
+	if len(added)+len(updated) > 0 {
+		if err := writeProfiles(cfg.ProfilesFile, profiles); err != nil {
+			return err
+		}
+	}
+
+	printProfileNames("Added", added)
+	printProfileNames("Overwritten", updated)
+	printProfileNames("Unchanged", unchanged)
+	return nil
+}
+
+func printProfileNames(label string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Printf("%s: %s\n", label, strings.Join(names, ", "))
+}
+
+func shareableProfile(p Profile) (Profile, []string) {
+	var stripped []string
+
+	if dbURL, ok := stripDBURLPassword(p.DBURL); ok {
+		p.DBURL = dbURL
+		stripped = append(stripped, "db_url password")
+	}
+	if readURL, ok := stripDBURLPassword(p.ReadURL); ok {
+		p.ReadURL = readURL
+		stripped = append(stripped, "read_url password")
+	}
+	if baseURL, ok := stripURLUserinfo(p.LLMBaseURL); ok {
+		p.LLMBaseURL = baseURL
+		stripped = append(stripped, "llm_base_url credentials")
+	}
+	if proxy, ok := stripURLUserinfo(p.LLMProxy); ok {
+		p.LLMProxy = proxy
+		stripped = append(stripped, "llm_proxy credentials")
+	}
+
+	headers := make([]string, 0, len(p.LLMHeaders))
+	for _, h := range p.LLMHeaders {
+		key, _, _ := strings.Cut(h, ":")
+		if isSecretHeader(key) {
+			stripped = append(stripped, fmt.Sprintf("llm_header %q", strings.TrimSpace(key)))
+			continue
+		}
+		headers = append(headers, h)
+	}
+	p.LLMHeaders = nil
+	if len(headers) > 0 {
+		p.LLMHeaders = headers
+	}
+
+	return p, stripped
+}
+
+func stripDBURLPassword(raw string) (string, bool) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return raw, false
+	}
+
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return raw, false
+		}
+		stripped := false
+		if u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.User(u.User.Username())
+				stripped = true
+			}
+		}
+		if query, ok := stripPasswordParams(u.RawQuery); ok {
+			u.RawQuery = query
+			stripped = true
+		}
+		if !stripped {
+			return raw, false
+		}
+		return u.String(), true
+	}
+
+	if isPostgresKeywordDSN(s) {
+		if !keywordDSNPasswordPattern.MatchString(s) {
+			return raw, false
+		}
+		out := keywordDSNPasswordPattern.ReplaceAllString(s, "")
+		return strings.Join(strings.Fields(out), " "), true
+	}
+
+	if m := mysqlDSNPattern.FindStringSubmatchIndex(s); m != nil && m[2] >= 0 {
+		if user, _, ok := strings.Cut(s[m[2]:m[3]], ":"); ok {
+			return user + s[m[3]:], true
+		}
+	}
+
+	return raw, false
+}
+
+// stripPasswordParams keeps the remaining parameters in their original order,
+// which url.Values.Encode would not.
+func stripPasswordParams(rawQuery string) (string, bool) {
+	if rawQuery == "" {
+		return rawQuery, false
+	}
+	params := strings.Split(rawQuery, "&")
+	kept := make([]string, 0, len(params))
+	for _, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if k, err := url.QueryUnescape(key); err == nil && (strings.EqualFold(k, "password") || strings.EqualFold(k, "sslpassword")) {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	if len(kept) == len(params) {
+		return rawQuery, false
+	}
+	return strings.Join(kept, "&"), true
+}
+
+func stripURLUserinfo(raw string) (string, bool) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return raw, false
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return raw, false
+	}
+	u.User = nil
+	return u.String(), true
+}
+
+func isSecretHeader(key string) bool {
+	k := strings.ToLower(strings.TrimSpace(key))
+	for _, marker := range []string{"auth", "key", "token", "secret", "cookie"} {
+		if strings.Contains(k, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateProfileAction(action string, args []string, bundle string) error {
+	switch action {
+	case profileActionExport:
+		return nil
+	case profileActionImport:
+		if len(args) > 0 || bundle == "" {
+			return errors.New("usage: dbquery profile import <file> [--overwrite]")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported profile action %q (expected export|import)", action)
+	}
+}
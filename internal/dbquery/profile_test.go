@@ -0,0 +1,111 @@
+package dbquery
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseQueryConfigProfileFromEnv(t *testing.T) {
+	args := testQueryArgs(t, "--api-key", "test", "--query", "count users")
+	profiles := args[3]
+	if err := writeProfiles(profiles, map[string]Profile{
+		"dev":  {DBType: "sqlite", DBURL: "dev.db"},
+		"prod": {DBType: "sqlite", DBURL: "prod.db"},
+	}); err != nil {
+		t.Fatalf("writeProfiles returned error: %v", err)
+	}
+	t.Setenv("DBQUERY_PROFILE", "dev")
+
+	cfg, err := parseQueryConfig(modeQuery, args)
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if cfg.Profile != "dev" || cfg.DBURL != "dev.db" {
+		t.Fatalf("expected DBQUERY_PROFILE to select dev, got profile=%q db=%q", cfg.Profile, cfg.DBURL)
+	}
+
+	cfg, err = parseQueryConfig(modeQuery, append(args, "--profile", "prod"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if cfg.Profile != "prod" || cfg.DBURL != "prod.db" {
+		t.Fatalf("expected --profile to override DBQUERY_PROFILE, got profile=%q db=%q", cfg.Profile, cfg.DBURL)
+	}
+}
+
+func TestParseQueryConfigProfileReadURLFollowsDBURL(t *testing.T) {
+	args := testQueryArgs(t, "--api-key", "test", "--query", "count users", "--profile", "prod")
+	if err := writeProfiles(args[3], map[string]Profile{
+		"prod":    {DBType: "postgres", DBURL: "postgres://primary/app", ReadURL: "postgres://replica/app"},
+		"staging": {Inherit: "prod", DBURL: "postgres://staging/app"},
+	}); err != nil {
+		t.Fatalf("writeProfiles returned error: %v", err)
+	}
+
+	cfg, err := parseQueryConfig(modeQuery, args)
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if cfg.ReadURL != "postgres://replica/app" {
+		t.Fatalf("expected the profile read url with its own db url, got %q", cfg.ReadURL)
+	}
+
+	cfg, err = parseQueryConfig(modeQuery, append(args, "--db-url", "postgres://other/app"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if cfg.ReadURL != "" {
+		t.Fatalf("profile read url should not pair with a different --db-url, got %q", cfg.ReadURL)
+	}
+
+	cfg, err = parseQueryConfig(modeQuery, append(args, "--db-url", "postgres://other/app", "--read-url", "postgres://other-replica/app"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if cfg.ReadURL != "postgres://other-replica/app" {
+		t.Fatalf("expected an explicit --read-url to apply, got %q", cfg.ReadURL)
+	}
+
+	cfg, err = parseQueryConfig(modeQuery, append(args[:len(args)-2:len(args)-2], "--profile", "staging"))
+	if err != nil {
+		t.Fatalf("parseQueryConfig returned error: %v", err)
+	}
+	if cfg.DBURL != "postgres://staging/app" || cfg.ReadURL != "" {
+		t.Fatalf("inherited read url should not pair with a child db url, got db=%q read=%q", cfg.DBURL, cfg.ReadURL)
+	}
+}
+
+func TestProfileInheritance(t *testing.T) {
+	dir := t.TempDir()
+	profiles := filepath.Join(dir, "profiles.json")
+	if err := writeProfiles(profiles, map[string]Profile{
+		"base":   {DBType: "postgres", DBURL: "postgres://db/app", Model: "gpt-4o-mini", Limit: 50, AllowWrite: true},
+		"fast":   {Inherit: "base", Model: "gpt-4o", Limit: 5},
+		"faster": {Inherit: "fast", Temperature: 0.2},
+		"loop-a": {Inherit: "loop-b"},
+		"loop-b": {Inherit: "loop-a"},
+		"orphan": {Inherit: "missing"},
+	}); err != nil {
+		t.Fatalf("writeProfiles returned error: %v", err)
+	}
+
+	chain, err := loadProfileChain(profiles, "faster")
+	if err != nil {
+		t.Fatalf("loadProfileChain returned error: %v", err)
+	}
+	cfg := DefaultConfig()
+	for _, p := range chain {
+		applyProfileDefaults(&cfg, p)
+	}
+	if cfg.DBURL != "postgres://db/app" || cfg.Model != "gpt-4o" || cfg.Limit != 5 || !cfg.AllowWrite || cfg.Temperature != 0.2 {
+		t.Fatalf("unexpected inherited config: db=%q model=%q limit=%d write=%v temp=%v", cfg.DBURL, cfg.Model, cfg.Limit, cfg.AllowWrite, cfg.Temperature)
+	}
+
+	if _, err := loadProfileChain(profiles, "loop-a"); err == nil || !strings.Contains(err.Error(), "cycle: loop-a -> loop-b -> loop-a") {
+		t.Fatalf("expected inheritance cycle error, got %v", err)
+	}
+	if _, err := loadProfileChain(profiles, "orphan"); err == nil || !strings.Contains(err.Error(), `inherits from "missing"`) {
+		t.Fatalf("expected missing parent error, got %v", err)
+	}
+}
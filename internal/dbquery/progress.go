@@ -0,0 +1,60 @@
+package dbquery
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const (
+	progressDelay    = 300 * time.Millisecond
+	progressInterval = 100 * time.Millisecond
+)
+
+func startProgress(cfg Config, label string) func() {
+	if cfg.NoProgress || cfg.Verbose || !isTerminal(os.Stderr) {
+		return func() {}
+	}
+	return runProgress(os.Stderr, label, progressDelay, progressInterval)
+}
+
+func runProgress(w io.Writer, label string, delay, interval time.Duration) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	start := time.Now()
+
+	go func() {
+		defer close(done)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			elapsed := time.Since(start).Truncate(time.Second)
+			fmt.Fprintf(w, "\r%s %s (elapsed: %s)", spinnerFrames[frame%len(spinnerFrames)], label, elapsed)
+			select {
+			case <-stop:
+				fmt.Fprint(w, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package dbquery
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunProgressRendersAndClears(t *testing.T) {
+	var buf bytes.Buffer
+	stop := runProgress(&buf, "Generating SQL...", 0, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	stop()
+	stop()
+
+	out := buf.String()
+	if !strings.Contains(out, "Generating SQL... (elapsed: 0s)") {
+		t.Fatalf("expected spinner label with elapsed time, got %q", out)
+	}
+	if !strings.HasSuffix(out, "\r\033[K") {
+		t.Fatalf("expected spinner line to be cleared, got %q", out)
+	}
+}
+
+func TestRunProgressSkipsFastOperations(t *testing.T) {
+	var buf bytes.Buffer
+	stop := runProgress(&buf, "Running query...", time.Hour, time.Millisecond)
+	stop()
+
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output before the delay elapses, got %q", buf.String())
+	}
+}
@@ -0,0 +1,55 @@
+package dbquery
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// stdin is the only reader of os.Stdin: a second buffered reader would keep
+// input the first had already read ahead, such as the answer to a prompt.
+var stdin = bufio.NewReader(os.Stdin)
+
+func promptYesNo(question string, defaultYes bool) (bool, error) {
+	if defaultYes {
+		fmt.Fprintf(os.Stderr, "%s [Y/n]: ", question)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			if strings.TrimSpace(line) == "" {
+				return false, nil
+			}
+		} else if errors.Is(err, os.ErrClosed) {
+			return false, nil
+		} else if len(strings.TrimSpace(line)) == 0 {
+			return false, err
+		}
+	}
+
+	answer := strings.ToLower(strings.TrimSpace(line))
+	switch answer {
+	case "":
+		return defaultYes, nil
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	default:
+		return false, nil
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,77 @@
+package dbquery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var sqlReservedWords = map[string]struct{}{
+	"all": {}, "alter": {}, "analyze": {}, "and": {}, "any": {}, "as": {}, "asc": {},
+	"between": {}, "both": {}, "by": {}, "case": {}, "cast": {}, "check": {}, "collate": {},
+	"column": {}, "constraint": {}, "create": {}, "cross": {}, "current_date": {},
+	"current_time": {}, "current_timestamp": {}, "current_user": {}, "database": {},
+	"default": {}, "delete": {}, "desc": {}, "describe": {}, "distinct": {}, "drop": {},
+	"else": {}, "end": {}, "except": {}, "exists": {}, "explain": {}, "false": {},
+	"fetch": {}, "for": {}, "foreign": {}, "from": {}, "full": {}, "grant": {}, "group": {},
+	"having": {}, "in": {}, "index": {}, "inner": {}, "insert": {}, "intersect": {},
+	"interval": {}, "into": {}, "is": {}, "join": {}, "key": {}, "leading": {}, "left": {},
+	"like": {}, "limit": {}, "natural": {}, "not": {}, "null": {}, "offset": {}, "on": {},
+	"or": {}, "order": {}, "outer": {}, "primary": {}, "range": {}, "read": {},
+	"references": {}, "rename": {}, "replace": {}, "right": {}, "row": {}, "rows": {},
+	"schema": {}, "select": {}, "session_user": {}, "set": {}, "table": {}, "then": {},
+	"to": {}, "top": {}, "trailing": {}, "true": {}, "union": {}, "unique": {},
+	"update": {}, "user": {}, "using": {}, "values": {}, "when": {}, "where": {},
+	"window": {}, "with": {},
+}
+
+func isReservedWord(name string) bool {
+	_, ok := sqlReservedWords[strings.ToLower(strings.Trim(name, "\"`[]"))]
+	return ok
+}
+
+func reservedIdentifiers(tables []tableDef) []string {
+	seen := map[string]struct{}{}
+	var out []string
+	add := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			out = append(out, name)
+		}
+	}
+
+	for _, t := range tables {
+		for _, part := range strings.Split(t.Name, ".") {
+			if isReservedWord(part) {
+				add(t.Name)
+				break
+			}
+		}
+		for _, col := range t.Columns {
+			fields := strings.Fields(col)
+			if len(fields) > 0 && isReservedWord(fields[0]) {
+				add(t.Name + "." + fields[0])
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func identifierQuoteExample(dialect string) string {
+	switch dialect {
+	case "mysql":
+		return "backticks (`name`)"
+	case dialectSQLServer:
+		return "square brackets ([name])"
+	default:
+		return `double quotes ("name")`
+	}
+}
+
+func quoteIdentifiersInstruction(cfg Config) string {
+	if !cfg.QuoteIdentifiers {
+		return ""
+	}
+	return fmt.Sprintf("Quote every table and column name with %s.", identifierQuoteExample(sqlDialect(cfg.DBType)))
+}
@@ -0,0 +1,47 @@
+package dbquery
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReservedIdentifiers(t *testing.T) {
+	tables := []tableDef{
+		{Name: "order", Columns: []string{"id INTEGER", "total REAL"}},
+		{Name: "users", Columns: []string{"id INTEGER", "select TEXT NOT NULL", "user_name TEXT"}},
+		{Name: "public.group", Columns: []string{"id INTEGER"}},
+	}
+
+	got := strings.Join(reservedIdentifiers(tables), ",")
+	if got != "order,public.group,users.select" {
+		t.Fatalf("unexpected reserved identifiers: %q", got)
+	}
+}
+
+func TestBuildSchemaContextFlagsReservedIdentifiers(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE "order" (id INTEGER PRIMARY KEY, "desc" TEXT)`)
+	cfg := Config{DBType: "sqlite", SchemaMaxTables: 10}
+
+	schemaContext, err := buildSchemaContext(context.Background(), db, cfg)
+	if err != nil {
+		t.Fatalf("buildSchemaContext returned error: %v", err)
+	}
+	if !strings.Contains(schemaContext, `Reserved-word identifiers, always quote with double quotes ("name"): order, order.desc`) {
+		t.Fatalf("expected reserved identifiers in schema context, got:\n%s", schemaContext)
+	}
+}
+
+func TestBuildLLMPromptsQuoteIdentifiers(t *testing.T) {
+	cfg := Config{DBType: "mysql", Limit: 10}
+	systemPrompt, _ := buildLLMPrompts(cfg, "", "list orders")
+	if strings.Contains(systemPrompt, "Quote every") {
+		t.Fatalf("did not expect quoting instruction by default:\n%s", systemPrompt)
+	}
+
+	cfg.QuoteIdentifiers = true
+	systemPrompt, _ = buildLLMPrompts(cfg, "", "list orders")
+	if !strings.Contains(systemPrompt, "Quote every table and column name with backticks (`name`).") {
+		t.Fatalf("expected mysql quoting instruction:\n%s", systemPrompt)
+	}
+}
@@ -3,52 +3,187 @@ package dbquery
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+const (
+	tableStyleBox        = "box"
+	tableStyleSimple     = "simple"
+	tableStyleBorderless = "borderless"
+)
+
+const (
+	numberFormatRaw     = "raw"
+	numberFormatGrouped = "grouped"
+)
+
+const (
+	headerCaseSnake = "snake"
+	headerCaseTitle = "title"
+	headerCaseUpper = "upper"
+	headerCaseLower = "lower"
 )
 
-func renderOutput(format string, columns []string, rows []map[string]any) (string, error) {
+const outputSQLInsert = "sql-insert"
+
+type renderOptions struct {
+	TableStyle   string
+	NumberFormat string
+	HeaderCase   string
+	Limit        int
+	InsertTable  string
+	Dialect      string
+	JSONEnvelope bool
+	SQL          string
+	Truncated    bool
+}
+
+func renderOptionsFromConfig(cfg Config) renderOptions {
+	return renderOptions{
+		TableStyle:   cfg.TableStyle,
+		NumberFormat: cfg.NumberFormat,
+		HeaderCase:   cfg.HeaderCase,
+		InsertTable:  cfg.InsertTable,
+		Dialect:      sqlDialect(cfg.DBType),
+		JSONEnvelope: cfg.JSONEnvelope,
+	}
+}
+
+func renderOutput(format string, columns []string, rows []map[string]any, opts renderOptions) (string, error) {
 	switch format {
 	case "json":
-		payload, err := json.MarshalIndent(rows, "", "  ")
+		var v any = rows
+		if opts.JSONEnvelope {
+			v = newJSONEnvelope(columns, rows, opts)
+		}
+		payload, err := json.MarshalIndent(v, "", "  ")
 		if err != nil {
 			return "", fmt.Errorf("marshal json output: %w", err)
 		}
 		return string(payload), nil
 	case "table":
-		return renderTable(columns, rows), nil
+		return renderTable(columns, rows, opts), nil
+	case outputSQLInsert:
+		return renderSQLInsert(columns, rows, opts)
 	default:
 		return "", fmt.Errorf("unsupported output format %q", format)
 	}
 }
 
-func renderTable(columns []string, rows []map[string]any) string {
+type jsonEnvelope struct {
+	Columns   []string         `json:"columns"`
+	Rows      []map[string]any `json:"rows"`
+	RowCount  int              `json:"row_count"`
+	Truncated bool             `json:"truncated"`
+	SQL       string           `json:"sql"`
+}
+
+func newJSONEnvelope(columns []string, rows []map[string]any, opts renderOptions) jsonEnvelope {
+	if columns == nil {
+		columns = []string{}
+	}
+	if rows == nil {
+		rows = []map[string]any{}
+	}
+	return jsonEnvelope{
+		Columns:   columns,
+		Rows:      rows,
+		RowCount:  len(rows),
+		Truncated: opts.Truncated || (opts.Limit > 0 && len(rows) == opts.Limit),
+		SQL:       opts.SQL,
+	}
+}
+
+func isTableStyle(v string) bool {
+	return v == tableStyleBox || v == tableStyleSimple || v == tableStyleBorderless
+}
+
+func isHeaderCase(v string) bool {
+	return v == headerCaseSnake || v == headerCaseTitle || v == headerCaseUpper || v == headerCaseLower
+}
+
+func formatHeader(column, headerCase string) string {
+	switch headerCase {
+	case headerCaseUpper:
+		return strings.ToUpper(column)
+	case headerCaseLower:
+		return strings.ToLower(column)
+	case headerCaseTitle:
+		words := strings.FieldsFunc(column, func(r rune) bool { return r == '_' || unicode.IsSpace(r) })
+		if len(words) == 0 {
+			return column
+		}
+		for i, w := range words {
+			runes := []rune(strings.ToLower(w))
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+		return strings.Join(words, " ")
+	default:
+		return column
+	}
+}
+
+func renderTable(columns []string, rows []map[string]any, opts renderOptions) string {
 	if len(columns) == 0 {
-		return "No rows returned."
+		if len(rows) == 0 {
+			return "Query returned no columns and no rows."
+		}
+		return fmt.Sprintf("Query returned %d %s but no columns to display.", len(rows), pluralize(len(rows), "row", "rows"))
 	}
 
+	headers := make([]string, len(columns))
 	widths := make([]int, len(columns))
 	for i, col := range columns {
-		widths[i] = len(col)
+		headers[i] = formatHeader(col, opts.HeaderCase)
+		widths[i] = displayWidth(headers[i])
 	}
 
 	stringRows := make([][]string, 0, len(rows))
 	for _, row := range rows {
 		line := make([]string, len(columns))
 		for i, col := range columns {
-			v := formatCellValue(row[col])
+			v := formatTableCell(row[col], opts)
 			line[i] = v
-			if len(v) > widths[i] {
-				widths[i] = len(v)
+			if w := displayWidth(v); w > widths[i] {
+				widths[i] = w
 			}
 		}
 		stringRows = append(stringRows, line)
 	}
 
-	hline := buildHorizontalLine(widths)
 	var b strings.Builder
+	switch opts.TableStyle {
+	case tableStyleSimple, tableStyleBorderless:
+		b.WriteString(buildPlainRow(headers, widths))
+		b.WriteByte('\n')
+		if opts.TableStyle == tableStyleSimple {
+			underline := make([]string, len(widths))
+			for i, w := range widths {
+				underline[i] = strings.Repeat("-", w)
+			}
+			b.WriteString(buildPlainRow(underline, widths))
+			b.WriteByte('\n')
+		}
+		for _, line := range stringRows {
+			b.WriteString(buildPlainRow(line, widths))
+			b.WriteByte('\n')
+		}
+		if footer := tableFooter(columns, rows, opts.Limit); footer != "" {
+			b.WriteString(footer)
+		}
+		return strings.TrimRight(b.String(), "\n")
+	}
+
+	hline := buildHorizontalLine(widths)
 	b.WriteString(hline)
 	b.WriteByte('\n')
-	b.WriteString(buildTableRow(columns, widths))
+	b.WriteString(buildTableRow(headers, widths))
 	b.WriteByte('\n')
 	b.WriteString(hline)
 	b.WriteByte('\n')
@@ -59,13 +194,37 @@ func renderTable(columns []string, rows []map[string]any) string {
 	}
 
 	b.WriteString(hline)
-	if len(rows) == 0 {
-		b.WriteString("\n(0 rows)")
+	if footer := tableFooter(columns, rows, opts.Limit); footer != "" {
+		b.WriteString("\n" + footer)
 	}
 
 	return b.String()
 }
 
+func tableFooter(columns []string, rows []map[string]any, limit int) string {
+	if len(rows) == 0 {
+		return "(0 rows)"
+	}
+	if limit > 0 && len(rows) == limit {
+		return fmt.Sprintf("(showing %d %s; limit reached — there may be more)", len(rows), pluralize(len(rows), "row", "rows"))
+	}
+	for _, row := range rows {
+		for _, col := range columns {
+			if row[col] != nil {
+				return ""
+			}
+		}
+	}
+	return fmt.Sprintf("(%d %s, all values NULL)", len(rows), pluralize(len(rows), "row", "rows"))
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
 func buildHorizontalLine(widths []int) string {
 	var b strings.Builder
 	b.WriteByte('+')
@@ -82,7 +241,7 @@ func buildTableRow(values []string, widths []int) string {
 	for i, v := range values {
 		b.WriteByte(' ')
 		b.WriteString(v)
-		padding := widths[i] - len(v)
+		padding := widths[i] - displayWidth(v)
 		if padding > 0 {
 			b.WriteString(strings.Repeat(" ", padding))
 		}
@@ -92,10 +251,131 @@ func buildTableRow(values []string, widths []int) string {
 	return b.String()
 }
 
+func buildPlainRow(values []string, widths []int) string {
+	var b strings.Builder
+	for i, v := range values {
+		if i > 0 {
+			b.WriteString("  ")
+		}
+		b.WriteString(v)
+		padding := widths[i] - displayWidth(v)
+		if padding > 0 && i < len(values)-1 {
+			b.WriteString(strings.Repeat(" ", padding))
+		}
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r), r == '\u200d':
+		case isWideRune(r):
+			w += 2
+		default:
+			w++
+		}
+	}
+	return w
+}
+
+func truncateDisplay(s string, maxWidth int) string {
+	if maxWidth <= 0 || displayWidth(s) <= maxWidth {
+		return s
+	}
+
+	var b strings.Builder
+	w := 0
+	for _, r := range s {
+		rw := displayWidth(string(r))
+		if w+rw > maxWidth-1 {
+			break
+		}
+		b.WriteRune(r)
+		w += rw
+	}
+	b.WriteString("…")
+	return b.String()
+}
+
+func isWideRune(r rune) bool {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return true
+	default:
+		return false
+	}
+}
+
+func formatTableCell(v any, opts renderOptions) string {
+	if opts.NumberFormat == numberFormatGrouped {
+		if grouped, ok := formatGroupedNumber(v); ok {
+			return grouped
+		}
+	}
+	return formatCellValue(v)
+}
+
+func formatGroupedNumber(v any) (string, bool) {
+	var s string
+	switch n := v.(type) {
+	case int:
+		s = strconv.FormatInt(int64(n), 10)
+	case int32:
+		s = strconv.FormatInt(int64(n), 10)
+	case int64:
+		s = strconv.FormatInt(n, 10)
+	case uint:
+		s = strconv.FormatUint(uint64(n), 10)
+	case uint32:
+		s = strconv.FormatUint(uint64(n), 10)
+	case uint64:
+		s = strconv.FormatUint(n, 10)
+	case float32:
+		s = strconv.FormatFloat(float64(n), 'f', -1, 32)
+	case float64:
+		if math.IsInf(n, 0) || math.IsNaN(n) {
+			return "", false
+		}
+		s = strconv.FormatFloat(n, 'f', -1, 64)
+	default:
+		return "", false
+	}
+	return groupDigits(s), true
+}
+
+func groupDigits(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	intPart, frac := s, ""
+	if idx := strings.IndexByte(s, '.'); idx >= 0 {
+		intPart, frac = s[:idx], s[idx:]
+	}
+
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String() + frac
+}
+
 func formatCellValue(v any) string {
 	if v == nil {
 		return "NULL"
 	}
+	if raw, ok := v.(json.RawMessage); ok {
+		v = string(raw)
+	}
+	if arr, ok := v.([]any); ok {
+		return formatArrayCell(arr)
+	}
 
 	str := fmt.Sprintf("%v", v)
 	str = strings.ReplaceAll(str, "\n", " ")
@@ -1,6 +1,7 @@
 package dbquery
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 )
@@ -9,7 +10,7 @@ func TestRenderOutputJSON(t *testing.T) {
 	columns := []string{"id", "name"}
 	rows := []map[string]any{{"id": 1, "name": "sam"}}
 
-	out, err := renderOutput("json", columns, rows)
+	out, err := renderOutput("json", columns, rows, renderOptions{})
 	if err != nil {
 		t.Fatalf("renderOutput returned error: %v", err)
 	}
@@ -25,7 +26,7 @@ func TestRenderOutputTable(t *testing.T) {
 	columns := []string{"id", "name"}
 	rows := []map[string]any{{"id": 1, "name": "sam"}}
 
-	out, err := renderOutput("table", columns, rows)
+	out, err := renderOutput("table", columns, rows, renderOptions{})
 	if err != nil {
 		t.Fatalf("renderOutput returned error: %v", err)
 	}
@@ -37,3 +38,232 @@ func TestRenderOutputTable(t *testing.T) {
 		}
 	}
 }
+
+func TestRenderTableStyles(t *testing.T) {
+	columns := []string{"id", "name"}
+	rows := []map[string]any{{"id": 1, "name": "sam"}, {"id": 22, "name": "alexandra"}}
+
+	simple := renderTable(columns, rows, renderOptions{TableStyle: tableStyleSimple})
+	wantSimple := "id  name\n--  ---------\n1   sam\n22  alexandra"
+	if simple != wantSimple {
+		t.Fatalf("unexpected simple table:\n%s\nwant:\n%s", simple, wantSimple)
+	}
+
+	borderless := renderTable(columns, rows, renderOptions{TableStyle: tableStyleBorderless})
+	wantBorderless := "id  name\n1   sam\n22  alexandra"
+	if borderless != wantBorderless {
+		t.Fatalf("unexpected borderless table:\n%s\nwant:\n%s", borderless, wantBorderless)
+	}
+	if strings.ContainsAny(borderless, "|+") {
+		t.Fatalf("borderless table should not contain border characters: %s", borderless)
+	}
+
+	box := renderTable(columns, rows, renderOptions{})
+	if !strings.HasPrefix(box, "+----+") {
+		t.Fatalf("default style should be box: %s", box)
+	}
+}
+
+func TestRenderTableUnicodeAlignment(t *testing.T) {
+	columns := []string{"name", "city"}
+	rows := []map[string]any{
+		{"name": "café", "city": "東京"},
+		{"name": "bob", "city": "Paris"},
+	}
+
+	out := renderTable(columns, rows, renderOptions{})
+	lines := strings.Split(out, "\n")
+	want := displayWidth(lines[0])
+	for _, line := range lines {
+		if got := displayWidth(line); got != want {
+			t.Fatalf("misaligned line %q: width %d, want %d\n%s", line, got, want, out)
+		}
+	}
+
+	if !strings.Contains(out, "| café | 東京  |") {
+		t.Fatalf("expected padded unicode cells:\n%s", out)
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{in: "abc", want: 3},
+		{in: "café", want: 4},
+		{in: "cafe\u0301", want: 4},
+		{in: "東京", want: 4},
+		{in: "ｱ", want: 1},
+	}
+	for _, tt := range tests {
+		if got := displayWidth(tt.in); got != tt.want {
+			t.Fatalf("displayWidth(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenderTableGroupedNumbers(t *testing.T) {
+	columns := []string{"n", "amount", "label"}
+	rows := []map[string]any{{"n": int64(1234567), "amount": -9876543.25, "label": "1000"}}
+
+	out := renderTable(columns, rows, renderOptions{NumberFormat: numberFormatGrouped})
+	for _, token := range []string{"1,234,567", "-9,876,543.25", "| 1000 "} {
+		if !strings.Contains(out, token) {
+			t.Fatalf("grouped table output missing %q:\n%s", token, out)
+		}
+	}
+
+	raw := renderTable(columns, rows, renderOptions{})
+	if strings.Contains(raw, "1,234,567") {
+		t.Fatalf("raw number format should not group digits:\n%s", raw)
+	}
+
+	js, err := renderOutput("json", columns, rows, renderOptions{NumberFormat: numberFormatGrouped})
+	if err != nil {
+		t.Fatalf("renderOutput json returned error: %v", err)
+	}
+	if !strings.Contains(js, "1234567") {
+		t.Fatalf("json output should stay raw: %s", js)
+	}
+}
+
+func TestGroupDigits(t *testing.T) {
+	tests := map[string]string{
+		"0":         "0",
+		"999":       "999",
+		"1000":      "1,000",
+		"-1234567":  "-1,234,567",
+		"12345.678": "12,345.678",
+	}
+	for in, want := range tests {
+		if got := groupDigits(in); got != want {
+			t.Fatalf("groupDigits(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTruncateDisplay(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{in: "short", width: 10, want: "short"},
+		{in: "exactly10!", width: 10, want: "exactly10!"},
+		{in: "show me all users created this week", width: 12, want: "show me all…"},
+		{in: "日本語のクエリ", width: 6, want: "日本…"},
+		{in: "anything", width: 0, want: "anything"},
+	}
+
+	for _, tt := range tests {
+		got := truncateDisplay(tt.in, tt.width)
+		if got != tt.want {
+			t.Fatalf("truncateDisplay(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+		if tt.width > 0 && displayWidth(got) > tt.width {
+			t.Fatalf("truncateDisplay(%q, %d) exceeds width: %q", tt.in, tt.width, got)
+		}
+	}
+}
+
+func TestRenderTableEdgeCases(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		rows    []map[string]any
+		style   string
+		want    string
+	}{
+		{name: "no columns no rows", want: "Query returned no columns and no rows."},
+		{name: "no columns with rows", rows: []map[string]any{{}, {}}, want: "Query returned 2 rows but no columns to display."},
+		{name: "no columns single row", rows: []map[string]any{{}}, want: "Query returned 1 row but no columns to display."},
+		{name: "columns no rows", columns: []string{"id"}, want: "(0 rows)"},
+		{name: "null-only rows", columns: []string{"a", "b"}, rows: []map[string]any{{"a": nil, "b": nil}}, want: "(1 row, all values NULL)"},
+		{name: "null-only rows simple", columns: []string{"a"}, rows: []map[string]any{{"a": nil}, {"a": nil}}, style: tableStyleSimple, want: "(2 rows, all values NULL)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := renderTable(tt.columns, tt.rows, renderOptions{TableStyle: tt.style})
+			if !strings.HasSuffix(out, tt.want) {
+				t.Fatalf("expected output to end with %q, got:\n%s", tt.want, out)
+			}
+		})
+	}
+
+	out := renderTable([]string{"a"}, []map[string]any{{"a": nil}, {"a": 1}}, renderOptions{})
+	if strings.Contains(out, "all values NULL") {
+		t.Fatalf("unexpected NULL-only note for mixed rows:\n%s", out)
+	}
+}
+
+func TestRenderTableLimitReachedFooter(t *testing.T) {
+	columns := []string{"id"}
+	rows := []map[string]any{{"id": 1}, {"id": 2}}
+
+	out := renderTable(columns, rows, renderOptions{Limit: 2})
+	if !strings.HasSuffix(out, "(showing 2 rows; limit reached — there may be more)") {
+		t.Fatalf("expected limit-reached footer:\n%s", out)
+	}
+
+	for _, opts := range []renderOptions{{Limit: 3}, {}} {
+		if out := renderTable(columns, rows, opts); strings.Contains(out, "limit reached") {
+			t.Fatalf("unexpected limit footer with %+v:\n%s", opts, out)
+		}
+	}
+}
+
+func TestRenderTableHeaderCase(t *testing.T) {
+	columns := []string{"user_id", "createdAt", "TOTAL_spent"}
+	rows := []map[string]any{{"user_id": 1, "createdAt": "2024-01-01", "TOTAL_spent": 9.5}}
+
+	tests := []struct {
+		headerCase string
+		want       string
+	}{
+		{headerCase: headerCaseSnake, want: "user_id  createdAt   TOTAL_spent"},
+		{headerCase: headerCaseTitle, want: "User Id  Createdat   Total Spent"},
+		{headerCase: headerCaseUpper, want: "USER_ID  CREATEDAT   TOTAL_SPENT"},
+		{headerCase: headerCaseLower, want: "user_id  createdat   total_spent"},
+	}
+	for _, tt := range tests {
+		out := renderTable(columns, rows, renderOptions{TableStyle: tableStyleBorderless, HeaderCase: tt.headerCase})
+		if header := strings.SplitN(out, "\n", 2)[0]; header != tt.want {
+			t.Fatalf("header case %s: got %q, want %q", tt.headerCase, header, tt.want)
+		}
+	}
+
+	out, err := renderOutput("json", columns, rows, renderOptions{HeaderCase: headerCaseTitle})
+	if err != nil || !strings.Contains(out, `"user_id": 1`) {
+		t.Fatalf("expected JSON keys to stay unchanged, got %s (%v)", out, err)
+	}
+}
+
+func TestRenderOutputJSONEnvelope(t *testing.T) {
+	columns := []string{"name", "id"}
+	rows := []map[string]any{{"id": 1, "name": "sam"}, {"id": 2, "name": "kim"}}
+
+	out, err := renderOutput("json", columns, rows, renderOptions{JSONEnvelope: true, SQL: "SELECT name, id FROM users LIMIT 2", Limit: 2})
+	if err != nil {
+		t.Fatalf("renderOutput returned error: %v", err)
+	}
+	var got struct {
+		Columns   []string         `json:"columns"`
+		Rows      []map[string]any `json:"rows"`
+		RowCount  int              `json:"row_count"`
+		Truncated bool             `json:"truncated"`
+		SQL       string           `json:"sql"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("expected an envelope object, got %s (%v)", out, err)
+	}
+	if strings.Join(got.Columns, ",") != "name,id" || len(got.Rows) != 2 || got.RowCount != 2 || !got.Truncated || got.SQL != "SELECT name, id FROM users LIMIT 2" {
+		t.Fatalf("unexpected envelope: %+v", got)
+	}
+
+	out, err = renderOutput("json", nil, nil, renderOptions{JSONEnvelope: true, Limit: 100})
+	if err != nil || !strings.Contains(out, `"columns": []`) || !strings.Contains(out, `"rows": []`) || !strings.Contains(out, `"truncated": false`) {
+		t.Fatalf("expected an empty envelope, got %s (%v)", out, err)
+	}
+}
@@ -0,0 +1,96 @@
+package dbquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+func checkRequireWhere(ctx context.Context, db DBTX, dbType, query string, threshold int64) error {
+	keyword := leadingKeyword(query)
+	if keyword != "select" && keyword != "with" {
+		return nil
+	}
+	if isBoundedQuery(query) {
+		return nil
+	}
+
+	dialect := sqlDialect(dbType)
+	var large []string
+	for _, table := range referencedTables(query) {
+		rows, ok, err := estimateTableRows(ctx, db, dialect, table)
+		if err != nil {
+			return fmt.Errorf("estimate rows for %s: %w", table, err)
+		}
+		if ok && rows > threshold {
+			large = append(large, fmt.Sprintf("%s (~%d rows)", table, rows))
+		}
+	}
+	if len(large) == 0 {
+		return nil
+	}
+	return wrapError(ErrUnboundedScan, fmt.Errorf(
+		"query has no WHERE or LIMIT and reads %s above --require-where %d: %s; add a filter or pass --force to run it anyway",
+		pluralize(len(large), "a table", "tables"), threshold, strings.Join(large, ", "),
+	))
+}
+
+func isBoundedQuery(query string) bool {
+	masked := maskStringLiterals(stripLeadingComments(query))
+	return indexTopLevelKeyword(masked, "where") >= 0 || hasLimitPattern.MatchString(masked)
+}
+
+func estimateTableRows(ctx context.Context, db DBTX, dialect, table string) (int64, bool, error) {
+	schema, name, qualified := strings.Cut(table, ".")
+	if !qualified {
+		name, schema = schema, ""
+	}
+
+	var (
+		query string
+		args  []any
+	)
+	switch dialect {
+	case "postgres":
+		query = `SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass($1)`
+		args = []any{table}
+	case "mysql":
+		query = `SELECT table_rows FROM information_schema.tables WHERE table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND table_name = ?`
+		args = []any{schema, name}
+	case "sqlite":
+		exists, err := sqliteTableExists(ctx, db, name)
+		if err != nil || !exists {
+			return 0, false, err
+		}
+		query = "SELECT COUNT(*) FROM " + quoteTableNameForDialect(table, dialect)
+	default:
+		return 0, false, fmt.Errorf("unsupported db type %q", dialect)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return 0, false, rows.Err()
+	}
+	var count *int64
+	if err := rows.Scan(&count); err != nil {
+		return 0, false, err
+	}
+	if count == nil || *count < 0 {
+		return 0, false, rows.Err()
+	}
+	return *count, true, rows.Err()
+}
+
+func sqliteTableExists(ctx context.Context, db DBTX, name string) (bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ? COLLATE NOCASE`, name)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
@@ -0,0 +1,97 @@
+package dbquery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsBoundedQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "SELECT * FROM events", want: false},
+		{query: "SELECT count(*) FROM events", want: false},
+		{query: "SELECT * FROM events WHERE id = 1", want: true},
+		{query: "SELECT * FROM events LIMIT 10;", want: true},
+		{query: "SELECT * FROM events e JOIN (SELECT * FROM users WHERE active) u ON u.id = e.user_id", want: false},
+		{query: "SELECT * FROM events WHERE note = 'x'", want: true},
+		{query: "SELECT 'where' FROM events", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isBoundedQuery(tt.query); got != tt.want {
+			t.Errorf("isBoundedQuery(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCheckRequireWhereSQLite(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE events (id INTEGER PRIMARY KEY)`,
+		`INSERT INTO events (id) VALUES (1), (2), (3)`,
+		`CREATE TABLE tags (id INTEGER PRIMARY KEY)`,
+		`INSERT INTO tags (id) VALUES (1)`,
+	)
+	ctx := context.Background()
+
+	err := checkRequireWhere(ctx, db, "sqlite", "SELECT count(*) FROM events JOIN tags ON tags.id = events.id", 2)
+	if !errors.Is(err, ErrUnboundedScan) || !strings.Contains(err.Error(), "events (~3 rows)") || strings.Contains(err.Error(), "tags") {
+		t.Fatalf("expected ErrUnboundedScan naming events only, got %v", err)
+	}
+	if ExitCode(err) != ExitSafety {
+		t.Fatalf("expected safety exit code, got %d", ExitCode(err))
+	}
+
+	for _, query := range []string{
+		"SELECT * FROM events WHERE id = 1",
+		"SELECT * FROM events LIMIT 1",
+		"SELECT * FROM tags",
+		"WITH missing AS (SELECT 1) SELECT * FROM missing",
+		"DELETE FROM events",
+	} {
+		if err := checkRequireWhere(ctx, db, "sqlite", query, 2); err != nil {
+			t.Fatalf("expected %q to pass, got %v", query, err)
+		}
+	}
+}
+
+func TestProcessRawSQLRequireWhereWithAutoLimit(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE events (id INTEGER PRIMARY KEY)`,
+		`INSERT INTO events (id) VALUES (1), (2), (3)`,
+	)
+	cfg := Config{Mode: modeQuery, DBType: "sqlite", Output: "json", Limit: 10, Timeout: 5 * time.Second, NoHistory: true, RequireWhere: 2, stdout: &bytes.Buffer{}, stderr: &bytes.Buffer{}}
+
+	if _, err := processRawSQL(context.Background(), db, cfg, "SELECT * FROM events"); !errors.Is(err, ErrUnboundedScan) {
+		t.Fatalf("expected the automatic LIMIT not to satisfy --require-where, got %v", err)
+	}
+	if _, err := processRawSQL(context.Background(), db, cfg, "SELECT * FROM events LIMIT 2"); err != nil {
+		t.Fatalf("expected an explicit LIMIT to pass, got %v", err)
+	}
+}
+
+func TestClientQueryRequireWhere(t *testing.T) {
+	srv := newTestLLMServer(t, "SELECT * FROM events")
+	cfg := DefaultConfig()
+	cfg.DBType = "sqlite"
+	cfg.DBURL = newTestSQLiteFile(t, `CREATE TABLE events (id INTEGER PRIMARY KEY)`, `INSERT INTO events (id) VALUES (1), (2), (3)`)
+	cfg.APIKey = "test-key"
+	cfg.LLMBaseURL = srv.URL
+	cfg.RequireWhere = 2
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	defer client.Close()
+
+	if _, _, err := client.Query(ctx, "all events"); !errors.Is(err, ErrUnboundedScan) {
+		t.Fatalf("expected ErrUnboundedScan from Client.Query, got %v", err)
+	}
+}
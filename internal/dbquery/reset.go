@@ -2,11 +2,14 @@ package dbquery
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
-	"strings"
+	"path/filepath"
+	"time"
 )
 
 type resetItem struct {
@@ -34,11 +37,16 @@ func runReset(cfg Config) error {
 	removed := make([]string, 0, len(items))
 	missing := make([]string, 0, len(items))
 	wouldRemove := make([]string, 0, len(items))
+	backups := make([]string, 0, len(items))
+	backupSuffix := ".bak-" + time.Now().UTC().Format("20060102T150405Z")
 
 	for _, item := range items {
 		if cfg.DryRun {
 			if _, err := os.Stat(item.path); err == nil {
-				wouldRemove = append(wouldRemove, fmt.Sprintf("%s (%s)", item.label, item.path))
+				wouldRemove = append(wouldRemove, describeResetItem(item))
+				if cfg.ResetBackup {
+					backups = append(backups, fmt.Sprintf("%s -> %s", item.path, item.path+backupSuffix))
+				}
 				continue
 			} else if errors.Is(err, os.ErrNotExist) {
 				missing = append(missing, fmt.Sprintf("%s (%s)", item.label, item.path))
@@ -48,7 +56,16 @@ func runReset(cfg Config) error {
 			}
 		}
 
-		err := os.Remove(item.path)
+		if cfg.ResetBackup {
+			backupPath := item.path + backupSuffix
+			if err := copyResetItem(item.path, backupPath); err == nil {
+				backups = append(backups, fmt.Sprintf("%s -> %s", item.path, backupPath))
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("back up %s: %w", item.label, err)
+			}
+		}
+
+		err := removeResetItem(item.path)
 		if err == nil {
 			removed = append(removed, fmt.Sprintf("%s (%s)", item.label, item.path))
 			continue
@@ -79,6 +96,17 @@ func runReset(cfg Config) error {
 		}
 	}
 
+	if len(backups) > 0 {
+		if cfg.DryRun {
+			fmt.Println("Would back up:")
+		} else {
+			fmt.Println("Backed up:")
+		}
+		for _, line := range backups {
+			fmt.Printf("- %s\n", line)
+		}
+	}
+
 	if len(missing) > 0 {
 		fmt.Println("Already missing:")
 		for _, line := range missing {
@@ -96,11 +124,25 @@ func resetItemsForTarget(cfg Config) []resetItem {
 	case "profile":
 		return []resetItem{{label: "profile", path: cfg.ProfilesFile}}
 	case "all":
-		return []resetItem{
+		items := []resetItem{
 			{label: "config", path: cfg.SettingsFile},
 			{label: "profile", path: cfg.ProfilesFile},
 			{label: "history", path: cfg.HistoryFile},
 		}
+		// Paths left unset are skipped rather than resolved to the user's real defaults.
+		if cfg.HistoryFile != "" {
+			items = append(items,
+				resetItem{label: "history prompts", path: cfg.HistoryFile + ".prompts"},
+				resetItem{label: "history key", path: cfg.HistoryFile + ".key"},
+			)
+		}
+		if cfg.SchemaCacheDir != "" {
+			items = append(items, resetItem{label: "schema cache", path: cfg.SchemaCacheDir})
+		}
+		if cfg.BookmarksFile != "" {
+			items = append(items, resetItem{label: "bookmarks", path: cfg.BookmarksFile})
+		}
+		return items
 	default:
 		return nil
 	}
@@ -109,31 +151,132 @@ func resetItemsForTarget(cfg Config) []resetItem {
 func confirmReset(target string, items []resetItem) (bool, error) {
 	fmt.Fprintf(os.Stderr, "Reset %s? This will delete:\n", target)
 	for _, item := range items {
-		fmt.Fprintf(os.Stderr, "- %s (%s)\n", item.label, item.path)
+		fmt.Fprintf(os.Stderr, "- %s\n", describeResetItem(item))
 	}
-	fmt.Fprint(os.Stderr, "Continue? [Y/n]: ")
+	return promptYesNo("Continue?", true)
+}
 
-	reader := bufio.NewReader(os.Stdin)
-	line, err := reader.ReadString('\n')
+func describeResetItem(item resetItem) string {
+	base := fmt.Sprintf("%s (%s)", item.label, item.path)
+	info, err := os.Stat(item.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return base + ": not found"
+	}
 	if err != nil {
-		if errors.Is(err, io.EOF) {
-			if strings.TrimSpace(line) == "" {
-				return false, nil
+		return fmt.Sprintf("%s: %v", base, err)
+	}
+
+	if info.IsDir() {
+		n, err := countResetDirFiles(item.path)
+		if err != nil {
+			return fmt.Sprintf("%s: %v", base, err)
+		}
+		return fmt.Sprintf("%s: %d %s", base, n, pluralize(n, "file", "files"))
+	}
+
+	detail := fmt.Sprintf("%d %s", info.Size(), pluralize(int(info.Size()), "byte", "bytes"))
+	if item.label == "history" {
+		if n, err := countHistoryLines(item.path); err == nil {
+			detail += fmt.Sprintf(", %d %s", n, pluralize(n, "entry", "entries"))
+		}
+	}
+	return base + ": " + detail
+}
+
+func countResetDirFiles(dir string) (int, error) {
+	n := 0
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+func removeResetItem(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return os.RemoveAll(path)
+	}
+	return os.Remove(path)
+}
+
+func copyResetItem(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return copyFile(src, dst)
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
 			}
-		} else if errors.Is(err, os.ErrClosed) {
-			return false, nil
-		} else if len(strings.TrimSpace(line)) == 0 {
-			return false, err
+			return os.Mkdir(target, info.Mode().Perm())
 		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
 	}
+	defer in.Close()
 
-	answer := strings.ToLower(strings.TrimSpace(line))
-	switch answer {
-	case "", "y", "yes":
-		return true, nil
-	case "n", "no":
-		return false, nil
-	default:
-		return false, nil
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func countHistoryLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n := 0
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			n++
+		}
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
 	}
 }
@@ -1,8 +1,10 @@
 package dbquery
 
 import (
+	"bufio"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -41,13 +43,31 @@ func TestParseResetConfig(t *testing.T) {
 	if !cfg.DryRun || cfg.ResetTarget != "all" {
 		t.Fatalf("unexpected dry-run parse result: %+v", cfg)
 	}
+
+	cfg, err = parseResetConfig([]string{"--dry-run", "all", "--schema-cache-dir", "/tmp/schema-cache"})
+	if err != nil {
+		t.Fatalf("parseResetConfig with --schema-cache-dir after the target returned error: %v", err)
+	}
+	if !cfg.DryRun || cfg.ResetTarget != "all" || cfg.SchemaCacheDir != "/tmp/schema-cache" {
+		t.Fatalf("unexpected parse result with flags after the target: %+v", cfg)
+	}
+
+	cfg, err = parseResetConfig([]string{"--dry-run", "all", "--bookmarks-file", "/tmp/x.json"})
+	if err != nil {
+		t.Fatalf("parseResetConfig with --bookmarks-file after the target returned error: %v", err)
+	}
+	if cfg.ResetTarget != "all" || cfg.BookmarksFile != "/tmp/x.json" {
+		t.Fatalf("unexpected parse result with --bookmarks-file after the target: %+v", cfg)
+	}
 }
 
 func TestResetItemsForTarget(t *testing.T) {
 	cfg := Config{
-		SettingsFile: "/tmp/settings.json",
-		ProfilesFile: "/tmp/profiles.json",
-		HistoryFile:  "/tmp/history.jsonl",
+		SettingsFile:   "/tmp/settings.json",
+		ProfilesFile:   "/tmp/profiles.json",
+		HistoryFile:    "/tmp/history.jsonl",
+		SchemaCacheDir: "/tmp/schema-cache",
+		BookmarksFile:  "/tmp/bookmarks.json",
 	}
 
 	cfg.ResetTarget = "config"
@@ -64,8 +84,57 @@ func TestResetItemsForTarget(t *testing.T) {
 
 	cfg.ResetTarget = "all"
 	items = resetItemsForTarget(cfg)
-	if len(items) != 3 {
-		t.Fatalf("expected 3 items for all, got %+v", items)
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.label)
+	}
+	if got := strings.Join(labels, ","); got != "config,profile,history,history prompts,history key,schema cache,bookmarks" {
+		t.Fatalf("unexpected items for all: %+v", items)
+	}
+}
+
+func TestRunResetAllRemovesSidecars(t *testing.T) {
+	dir := t.TempDir()
+	historyPath := filepath.Join(dir, "history.jsonl")
+	cacheDir := filepath.Join(dir, "schema-cache")
+	bookmarksPath := filepath.Join(dir, "bookmarks.json")
+	for _, p := range []string{historyPath + ".prompts", cacheDir} {
+		if err := os.MkdirAll(p, 0o700); err != nil {
+			t.Fatalf("create fixture dir: %v", err)
+		}
+	}
+	for _, p := range []string{historyPath, historyPath + ".key", filepath.Join(historyPath+".prompts", "1.txt.gz"), filepath.Join(cacheDir, "abc.json"), bookmarksPath} {
+		if err := os.WriteFile(p, []byte("{}"), 0o600); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+	}
+
+	cfg := Config{
+		Mode:           modeReset,
+		ResetTarget:    "all",
+		ResetBackup:    true,
+		Yes:            true,
+		SettingsFile:   filepath.Join(dir, "settings.json"),
+		ProfilesFile:   filepath.Join(dir, "profiles.json"),
+		HistoryFile:    historyPath,
+		SchemaCacheDir: cacheDir,
+		BookmarksFile:  bookmarksPath,
+	}
+	if got := describeResetItem(resetItem{label: "schema cache", path: cacheDir}); got != "schema cache ("+cacheDir+"): 1 file" {
+		t.Fatalf("unexpected directory description %q", got)
+	}
+	if err := runReset(cfg); err != nil {
+		t.Fatalf("runReset returned error: %v", err)
+	}
+
+	for _, p := range []string{historyPath, historyPath + ".key", historyPath + ".prompts", cacheDir, bookmarksPath} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Fatalf("%s should be removed, stat err: %v", p, err)
+		}
+	}
+	matches, err := filepath.Glob(filepath.Join(historyPath+".prompts.bak-*", "1.txt.gz"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected the prompts directory to be backed up, got %v (%v)", matches, err)
 	}
 }
 
@@ -109,3 +178,88 @@ func TestRunResetDryRunDoesNotDelete(t *testing.T) {
 		t.Fatalf("history file should remain in dry-run: %v", err)
 	}
 }
+
+func TestDescribeResetItem(t *testing.T) {
+	dir := t.TempDir()
+	historyPath := filepath.Join(dir, "history.jsonl")
+	if err := os.WriteFile(historyPath, []byte("{\"mode\":\"query\"}\n\n{\"mode\":\"chat\"}\n"), 0o644); err != nil {
+		t.Fatalf("write history fixture: %v", err)
+	}
+
+	got := describeResetItem(resetItem{label: "history", path: historyPath})
+	if want := "history (" + historyPath + "): 34 bytes, 2 entries"; got != want {
+		t.Fatalf("describeResetItem = %q, want %q", got, want)
+	}
+
+	missing := filepath.Join(dir, "settings.json")
+	got = describeResetItem(resetItem{label: "config", path: missing})
+	if want := "config (" + missing + "): not found"; got != want {
+		t.Fatalf("describeResetItem = %q, want %q", got, want)
+	}
+}
+
+func TestRunResetBackup(t *testing.T) {
+	dir := t.TempDir()
+	settingsPath := filepath.Join(dir, "settings.json")
+	if err := os.WriteFile(settingsPath, []byte(`{"api_key":"k"}`), 0o600); err != nil {
+		t.Fatalf("write settings fixture: %v", err)
+	}
+
+	cfg := Config{
+		Mode:         modeReset,
+		ResetTarget:  "all",
+		ResetBackup:  true,
+		Yes:          true,
+		SettingsFile: settingsPath,
+		ProfilesFile: filepath.Join(dir, "profiles.json"),
+		HistoryFile:  filepath.Join(dir, "history.jsonl"),
+	}
+
+	cfg.DryRun = true
+	if err := runReset(cfg); err != nil {
+		t.Fatalf("runReset dry-run returned error: %v", err)
+	}
+	if matches, _ := filepath.Glob(settingsPath + ".bak-*"); len(matches) != 0 {
+		t.Fatalf("dry-run should not create backups: %v", matches)
+	}
+
+	cfg.DryRun = false
+	if err := runReset(cfg); err != nil {
+		t.Fatalf("runReset returned error: %v", err)
+	}
+	if _, err := os.Stat(settingsPath); !os.IsNotExist(err) {
+		t.Fatalf("settings file should be removed, stat err: %v", err)
+	}
+
+	matches, err := filepath.Glob(settingsPath + ".bak-*")
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected one settings backup, got %v (%v)", matches, err)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil || string(data) != `{"api_key":"k"}` {
+		t.Fatalf("unexpected backup contents %q (%v)", data, err)
+	}
+	info, err := os.Stat(matches[0])
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("backup should keep file mode 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+	if others, _ := filepath.Glob(filepath.Join(dir, "profiles.json.bak-*")); len(others) != 0 {
+		t.Fatalf("missing files should not be backed up: %v", others)
+	}
+}
+
+func TestPromptYesNoSharesStdinReader(t *testing.T) {
+	saved := stdin
+	t.Cleanup(func() { stdin = saved })
+	stdin = bufio.NewReader(strings.NewReader("n\ny\nlist users\n"))
+
+	if ok, err := promptYesNo("Send this schema to the LLM?", true); err != nil || ok {
+		t.Fatalf("expected the first answer to decline, got %v (%v)", ok, err)
+	}
+	if ok, err := promptYesNo("Continue?", false); err != nil || !ok {
+		t.Fatalf("expected the second prompt to read its own line, got %v (%v)", ok, err)
+	}
+	if line, err := stdin.ReadString('\n'); err != nil || line != "list users\n" {
+		t.Fatalf("expected later input to stay unread, got %q (%v)", line, err)
+	}
+}
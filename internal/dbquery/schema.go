@@ -8,28 +8,66 @@ import (
 	"strings"
 )
 
+const (
+	relationTable            = "table"
+	relationView             = "view"
+	relationMaterializedView = "materialized view"
+)
+
 type tableDef struct {
-	Name    string
-	Columns []string
+	Name    string   `json:"name"`
+	Kind    string   `json:"kind"`
+	Columns []string `json:"columns"`
+	Checks  []string `json:"checks,omitempty"`
+}
+
+type introspectOptions struct {
+	Tables        []string
+	MaxTables     int
+	IncludeViews  bool
+	Constraints   bool
+	SystemSchemas bool
+}
+
+func introspectOptionsFromConfig(cfg Config) introspectOptions {
+	return introspectOptions{
+		Tables:        cfg.Tables,
+		MaxTables:     cfg.SchemaMaxTables,
+		IncludeViews:  !cfg.NoViews,
+		Constraints:   cfg.SchemaConstraints,
+		SystemSchemas: cfg.SystemSchemas,
+	}
 }
 
 func buildSchemaContext(ctx context.Context, db *sql.DB, cfg Config) (string, error) {
-	tables, err := introspectSchema(ctx, db, cfg.DBType, cfg.Tables, cfg.SchemaMaxTables)
+	if cfg.VerifySchema != "" {
+		if err := verifySchemaSnapshot(ctx, db, cfg); err != nil {
+			return "", err
+		}
+	}
+	tables, total, err := cachedIntrospectSchema(ctx, db, cfg)
 	if err != nil {
 		return "", err
 	}
+	if total > len(tables) {
+		fmt.Fprintf(os.Stderr, "warning: schema truncated to %d of %d tables; use --schema-max-tables or --tables to include others\n", len(tables), total)
+	}
 
 	var b strings.Builder
 	b.WriteString("Discovered schema:\n")
 	if len(tables) == 0 {
 		b.WriteString("(no tables discovered)\n")
 	} else {
-		for _, t := range tables {
-			b.WriteString("- ")
-			b.WriteString(t.Name)
-			b.WriteString(" (")
-			b.WriteString(strings.Join(t.Columns, ", "))
-			b.WriteString(")\n")
+		listing, omitted := formatSchemaTables(tables, cfg.SchemaMaxTokens)
+		b.WriteString(listing)
+		if omitted > 0 {
+			fmt.Fprintf(&b, "(%d more tables omitted to fit the schema token budget)\n", omitted)
+			if cfg.Verbose {
+				fmt.Fprintf(os.Stderr, "warning: schema context trimmed to %d tokens; %d tables omitted\n", cfg.SchemaMaxTokens, omitted)
+			}
+		}
+		if reserved := reservedIdentifiers(tables); len(reserved) > 0 {
+			fmt.Fprintf(&b, "Reserved-word identifiers, always quote with %s: %s\n", identifierQuoteExample(sqlDialect(cfg.DBType)), strings.Join(reserved, ", "))
 		}
 	}
 
@@ -48,72 +86,194 @@ func buildSchemaContext(ctx context.Context, db *sql.DB, cfg Config) (string, er
 	return b.String(), nil
 }
 
-func introspectSchema(ctx context.Context, db *sql.DB, dbType string, tableScope []string, maxTables int) ([]tableDef, error) {
-	filter := makeTableFilter(tableScope)
+func formatSchemaTables(tables []tableDef, maxTokens int) (string, int) {
+	var b strings.Builder
+	used := 0
+	for i, t := range tables {
+		line := formatTableLine(t, t.Columns)
+		cost := estimateTokens(line)
+		if maxTokens <= 0 || used+cost <= maxTokens {
+			b.WriteString(line)
+			used += cost
+			continue
+		}
+
+		columns := make([]string, 0, len(t.Columns))
+		for _, col := range t.Columns {
+			candidate := formatTableLine(t, append(columns, col, "..."))
+			if used+estimateTokens(candidate) > maxTokens {
+				break
+			}
+			columns = append(columns, col)
+		}
+		if len(columns) == 0 {
+			return b.String(), len(tables) - i
+		}
+		b.WriteString(formatTableLine(t, append(columns, "...")))
+		return b.String(), len(tables) - i - 1
+	}
+	return b.String(), 0
+}
+
+func formatTableLine(t tableDef, columns []string) string {
+	name := t.Name
+	if t.Kind != "" && t.Kind != relationTable {
+		name += " [" + t.Kind + "]"
+	}
+	line := "- " + name + " (" + strings.Join(columns, ", ") + ")"
+	if len(t.Checks) > 0 {
+		line += " " + strings.Join(t.Checks, " ")
+	}
+	return line + "\n"
+}
+
+func describeColumn(name, dataType string, notNull bool, dflt string, withDefault bool) string {
+	desc := strings.TrimSpace(name + " " + dataType)
+	if notNull {
+		desc += " NOT NULL"
+	}
+	dflt = strings.TrimSpace(dflt)
+	if !withDefault || dflt == "" || strings.EqualFold(dflt, "NULL") || strings.HasPrefix(strings.ToLower(dflt), "nextval(") {
+		return desc
+	}
+	return desc + " DEFAULT " + dflt
+}
+
+func formatCheck(clause string) string {
+	clause = strings.Join(strings.Fields(clause), " ")
+	if strings.HasPrefix(strings.ToUpper(clause), "CHECK") {
+		return clause
+	}
+	return "CHECK (" + clause + ")"
+}
+
+func sqliteCheckClauses(createSQL string) []string {
+	masked := maskStringLiterals(createSQL)
+	lower := strings.ToLower(masked)
+	out := make([]string, 0)
+	for i := 0; i < len(lower); i++ {
+		if !strings.HasPrefix(lower[i:], "check") || (i > 0 && isIdentChar(lower[i-1])) {
+			continue
+		}
+		j := i + len("check")
+		for j < len(lower) && (lower[j] == ' ' || lower[j] == '\t' || lower[j] == '\n' || lower[j] == '\r') {
+			j++
+		}
+		if j >= len(lower) || lower[j] != '(' {
+			continue
+		}
+		depth := 0
+		for k := j; k < len(masked); k++ {
+			switch masked[k] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 {
+				out = append(out, formatCheck(createSQL[i:k+1]))
+				i = k
+				break
+			}
+		}
+	}
+	return out
+}
+
+func confirmSchemaContext(cfg Config, schemaContext string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "Schema context to be sent to the LLM:\n%s\n", strings.TrimRight(schemaContext, "\n"))
+	fmt.Fprintf(os.Stderr, "Estimated schema tokens: ~%d\n", estimateTokens(schemaContext))
+
+	if cfg.DryRun {
+		return true, nil
+	}
+	return promptYesNo("Send this schema to the LLM?", true)
+}
 
-	switch dbType {
+func introspectSchema(ctx context.Context, db *sql.DB, dbType string, opts introspectOptions) ([]tableDef, int, error) {
+	filter := makeTableFilter(opts.Tables)
+
+	switch sqlDialect(dbType) {
 	case "sqlite":
-		return introspectSQLite(ctx, db, filter, maxTables)
+		return introspectSQLite(ctx, db, filter, opts)
 	case "postgres":
-		return introspectPostgres(ctx, db, filter, maxTables)
+		return introspectPostgres(ctx, db, filter, opts)
 	case "mysql":
-		return introspectMySQL(ctx, db, filter, maxTables)
+		return introspectMySQL(ctx, db, filter, opts)
 	default:
-		return nil, fmt.Errorf("unsupported db type %q", dbType)
+		return nil, 0, fmt.Errorf("unsupported db type %q", dbType)
 	}
 }
 
-func introspectSQLite(ctx context.Context, db *sql.DB, filter map[string]struct{}, maxTables int) ([]tableDef, error) {
+func introspectSQLite(ctx context.Context, db *sql.DB, filter map[string]struct{}, opts introspectOptions) ([]tableDef, int, error) {
+	typeFilter := "type = 'table'"
+	if opts.IncludeViews {
+		typeFilter = "type IN ('table', 'view')"
+	}
 	rows, err := db.QueryContext(ctx, `
-		SELECT name
+		SELECT name, type
 		FROM sqlite_master
-		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		WHERE `+typeFilter+` AND name NOT LIKE 'sqlite_%'
 		ORDER BY name`)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	tableNames := make([]string, 0)
+	relations := make([]tableDef, 0)
 	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
+		var tableName, kind string
+		if err := rows.Scan(&tableName, &kind); err != nil {
 			_ = rows.Close()
-			return nil, err
+			return nil, 0, err
 		}
 		if !allowTableName(tableName, filter) {
 			continue
 		}
-		tableNames = append(tableNames, tableName)
+		relations = append(relations, tableDef{Name: tableName, Kind: kind})
 	}
 	if err := rows.Err(); err != nil {
 		_ = rows.Close()
-		return nil, err
+		return nil, 0, err
 	}
 	if err := rows.Close(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	out := make([]tableDef, 0)
-	for _, tableName := range tableNames {
-		escaped := strings.ReplaceAll(tableName, `"`, `""`)
+	for _, rel := range relations {
+		if len(out) >= opts.MaxTables {
+			break
+		}
+		escaped := strings.ReplaceAll(rel.Name, `"`, `""`)
 		metaQuery := fmt.Sprintf(`SELECT * FROM "%s" LIMIT 0`, escaped)
 		colRows, err := db.QueryContext(ctx, metaQuery)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		colNames, err := colRows.Columns()
 		if err != nil {
 			_ = colRows.Close()
-			return nil, err
+			return nil, 0, err
 		}
 		colTypes, err := colRows.ColumnTypes()
 		if err != nil {
 			_ = colRows.Close()
-			return nil, err
+			return nil, 0, err
 		}
 		if err := colRows.Close(); err != nil {
-			return nil, err
+			return nil, 0, err
+		}
+
+		info, err := sqliteColumnInfo(ctx, db, rel.Name)
+		if err != nil {
+			return nil, 0, err
+		}
+		if opts.Constraints && rel.Kind == relationTable {
+			rel.Checks, err = sqliteChecks(ctx, db, rel.Name)
+			if err != nil {
+				return nil, 0, err
+			}
 		}
 
 		columns := make([]string, 0, len(colNames))
@@ -122,134 +282,268 @@ func introspectSQLite(ctx context.Context, db *sql.DB, filter map[string]struct{
 			if i < len(colTypes) {
 				colType = colTypes[i].DatabaseTypeName()
 			}
-			colDesc := strings.TrimSpace(name + " " + colType)
-			columns = append(columns, strings.TrimSpace(colDesc))
+			col := info[name]
+			columns = append(columns, describeColumn(name, colType, col.notNull, col.dflt, opts.Constraints))
 		}
 
-		out = append(out, tableDef{Name: tableName, Columns: columns})
-		if len(out) >= maxTables {
-			break
+		rel.Columns = columns
+		out = append(out, rel)
+	}
+	return out, len(relations), nil
+}
+
+type sqliteColumn struct {
+	notNull bool
+	dflt    string
+}
+
+func sqliteColumnInfo(ctx context.Context, db *sql.DB, table string) (map[string]sqliteColumn, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name, "notnull", COALESCE(dflt_value, '') FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	info := map[string]sqliteColumn{}
+	for rows.Next() {
+		var (
+			name    string
+			notNull int
+			dflt    string
+		)
+		if err := rows.Scan(&name, &notNull, &dflt); err != nil {
+			return nil, err
 		}
+		info[name] = sqliteColumn{notNull: notNull != 0, dflt: dflt}
 	}
-	return out, nil
+	return info, rows.Err()
 }
 
-func introspectPostgres(ctx context.Context, db *sql.DB, filter map[string]struct{}, maxTables int) ([]tableDef, error) {
-	tableRows, err := db.QueryContext(ctx, `
-		SELECT table_schema, table_name
+func sqliteChecks(ctx context.Context, db *sql.DB, table string) ([]string, error) {
+	var createSQL string
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(sql, '') FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&createSQL); err != nil {
+		return nil, err
+	}
+	return sqliteCheckClauses(createSQL), nil
+}
+
+func introspectPostgres(ctx context.Context, db *sql.DB, filter map[string]struct{}, opts introspectOptions) ([]tableDef, int, error) {
+	listQuery := `
+		SELECT table_schema, table_name, 'table' AS kind
 		FROM information_schema.tables
 		WHERE table_type = 'BASE TABLE'
+		  AND table_schema NOT IN ('pg_catalog', 'information_schema')`
+	if opts.IncludeViews {
+		listQuery = `
+		SELECT table_schema, table_name,
+		       CASE table_type WHEN 'VIEW' THEN 'view' ELSE 'table' END AS kind
+		FROM information_schema.tables
+		WHERE table_type IN ('BASE TABLE', 'VIEW')
 		  AND table_schema NOT IN ('pg_catalog', 'information_schema')
-		ORDER BY table_schema, table_name`)
+		UNION ALL
+		SELECT schemaname, matviewname, 'materialized view'
+		FROM pg_matviews
+		WHERE schemaname NOT IN ('pg_catalog', 'information_schema')`
+	}
+	if opts.SystemSchemas {
+		// System relations are listed after user tables so --schema-max-tables keeps user tables first.
+		listQuery = `
+		SELECT * FROM (` + strings.ReplaceAll(listQuery, "NOT IN ('pg_catalog', 'information_schema')", "IS NOT NULL") + `
+		) rels
+		ORDER BY table_schema IN ('pg_catalog', 'information_schema'), 1, 2`
+	} else {
+		listQuery += `
+		ORDER BY 1, 2`
+	}
+
+	tableRows, err := db.QueryContext(ctx, listQuery)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer tableRows.Close()
 
 	out := make([]tableDef, 0)
+	total := 0
 	for tableRows.Next() {
-		var schemaName, tableName string
-		if err := tableRows.Scan(&schemaName, &tableName); err != nil {
-			return nil, err
+		var schemaName, tableName, kind string
+		if err := tableRows.Scan(&schemaName, &tableName, &kind); err != nil {
+			return nil, 0, err
 		}
 
 		fullName := schemaName + "." + tableName
 		if !allowTableName(fullName, filter) {
 			continue
 		}
+		total++
+		if len(out) >= opts.MaxTables {
+			continue
+		}
 
-		colRows, err := db.QueryContext(ctx, `
-			SELECT column_name, data_type
+		columnQuery := `
+			SELECT column_name, data_type, is_nullable, COALESCE(column_default, '')
 			FROM information_schema.columns
 			WHERE table_schema = $1 AND table_name = $2
-			ORDER BY ordinal_position`, schemaName, tableName)
+			ORDER BY ordinal_position`
+		if kind == relationMaterializedView {
+			columnQuery = `
+			SELECT a.attname, format_type(a.atttypid, a.atttypmod), CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END, ''
+			FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2
+			  AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum`
+		}
+
+		colRows, err := db.QueryContext(ctx, columnQuery, schemaName, tableName)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		columns := make([]string, 0)
 		for colRows.Next() {
-			var colName, dataType string
-			if err := colRows.Scan(&colName, &dataType); err != nil {
+			var colName, dataType, nullable, dflt string
+			if err := colRows.Scan(&colName, &dataType, &nullable, &dflt); err != nil {
 				_ = colRows.Close()
-				return nil, err
+				return nil, 0, err
 			}
-			columns = append(columns, strings.TrimSpace(colName+" "+dataType))
+			columns = append(columns, describeColumn(colName, dataType, nullable == "NO", dflt, opts.Constraints))
 		}
 		if err := colRows.Err(); err != nil {
 			_ = colRows.Close()
-			return nil, err
+			return nil, 0, err
 		}
 		_ = colRows.Close()
 
-		out = append(out, tableDef{Name: fullName, Columns: columns})
-		if len(out) >= maxTables {
-			break
+		var checks []string
+		if opts.Constraints && kind == relationTable {
+			checks, err = queryCheckClauses(ctx, db, `
+				SELECT pg_get_constraintdef(con.oid)
+				FROM pg_constraint con
+				JOIN pg_class c ON c.oid = con.conrelid
+				JOIN pg_namespace n ON n.oid = c.relnamespace
+				WHERE n.nspname = $1 AND c.relname = $2 AND con.contype = 'c'
+				ORDER BY con.conname`, schemaName, tableName)
+			if err != nil {
+				return nil, 0, err
+			}
 		}
+
+		out = append(out, tableDef{Name: fullName, Kind: kind, Columns: columns, Checks: checks})
 	}
 
 	if err := tableRows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return out, nil
+	return out, total, nil
 }
 
-func introspectMySQL(ctx context.Context, db *sql.DB, filter map[string]struct{}, maxTables int) ([]tableDef, error) {
+func introspectMySQL(ctx context.Context, db *sql.DB, filter map[string]struct{}, opts introspectOptions) ([]tableDef, int, error) {
+	typeFilter := "table_type = 'BASE TABLE'"
+	if opts.IncludeViews {
+		typeFilter = "table_type IN ('BASE TABLE', 'VIEW')"
+	}
+	schemaFilter := "table_schema = DATABASE()"
+	if opts.SystemSchemas {
+		schemaFilter = "table_schema IN (DATABASE(), 'information_schema', 'performance_schema', 'mysql', 'sys')"
+	}
 	tableRows, err := db.QueryContext(ctx, `
-		SELECT table_name
+		SELECT table_schema, table_name, CASE table_type WHEN 'VIEW' THEN 'view' ELSE 'table' END,
+		       table_schema = DATABASE()
 		FROM information_schema.tables
-		WHERE table_schema = DATABASE()
-		ORDER BY table_name`)
+		WHERE `+schemaFilter+`
+		  AND `+typeFilter+`
+		ORDER BY table_schema <> DATABASE(), table_schema, table_name`)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer tableRows.Close()
 
 	out := make([]tableDef, 0)
+	total := 0
 	for tableRows.Next() {
-		var tableName string
-		if err := tableRows.Scan(&tableName); err != nil {
-			return nil, err
+		var schemaName, tableName, kind string
+		var current bool
+		if err := tableRows.Scan(&schemaName, &tableName, &kind, &current); err != nil {
+			return nil, 0, err
 		}
-		if !allowTableName(tableName, filter) {
+		name := tableName
+		if !current {
+			name = schemaName + "." + tableName
+		}
+		if !allowTableName(name, filter) {
+			continue
+		}
+		total++
+		if len(out) >= opts.MaxTables {
 			continue
 		}
 
 		colRows, err := db.QueryContext(ctx, `
-			SELECT column_name, data_type
+			SELECT column_name, data_type, column_type, is_nullable, COALESCE(column_default, '')
 			FROM information_schema.columns
-			WHERE table_schema = DATABASE()
+			WHERE table_schema = ?
 			  AND table_name = ?
-			ORDER BY ordinal_position`, tableName)
+			ORDER BY ordinal_position`, schemaName, tableName)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		columns := make([]string, 0)
 		for colRows.Next() {
-			var colName, dataType string
-			if err := colRows.Scan(&colName, &dataType); err != nil {
+			var colName, dataType, columnType, nullable, dflt string
+			if err := colRows.Scan(&colName, &dataType, &columnType, &nullable, &dflt); err != nil {
 				_ = colRows.Close()
-				return nil, err
+				return nil, 0, err
 			}
-			columns = append(columns, strings.TrimSpace(colName+" "+dataType))
+			if opts.Constraints {
+				dataType = columnType
+			}
+			columns = append(columns, describeColumn(colName, dataType, nullable == "NO", dflt, opts.Constraints))
 		}
 		if err := colRows.Err(); err != nil {
 			_ = colRows.Close()
-			return nil, err
+			return nil, 0, err
 		}
 		_ = colRows.Close()
 
-		out = append(out, tableDef{Name: tableName, Columns: columns})
-		if len(out) >= maxTables {
-			break
+		var checks []string
+		if opts.Constraints && kind == relationTable {
+			// information_schema.check_constraints only exists on MySQL 8.0.16+ and MariaDB 10.2+.
+			checks, _ = queryCheckClauses(ctx, db, `
+				SELECT cc.check_clause
+				FROM information_schema.table_constraints tc
+				JOIN information_schema.check_constraints cc
+				  ON cc.constraint_schema = tc.constraint_schema AND cc.constraint_name = tc.constraint_name
+				WHERE tc.table_schema = ? AND tc.table_name = ? AND tc.constraint_type = 'CHECK'
+				ORDER BY tc.constraint_name`, schemaName, tableName)
 		}
+
+		out = append(out, tableDef{Name: name, Kind: kind, Columns: columns, Checks: checks})
 	}
 
 	if err := tableRows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func queryCheckClauses(ctx context.Context, db *sql.DB, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	defer rows.Close()
+
+	checks := make([]string, 0)
+	for rows.Next() {
+		var clause string
+		if err := rows.Scan(&clause); err != nil {
+			return nil, err
+		}
+		checks = append(checks, formatCheck(clause))
+	}
+	return checks, rows.Err()
 }
 
 func makeTableFilter(tableScope []string) map[string]struct{} {
@@ -0,0 +1,143 @@
+package dbquery
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type schemaCacheEntry struct {
+	CreatedAt time.Time  `json:"created_at"`
+	Signature string     `json:"signature,omitempty"`
+	Tables    []tableDef `json:"tables"`
+	Total     int        `json:"total"`
+}
+
+func cachedIntrospectSchema(ctx context.Context, db *sql.DB, cfg Config) ([]tableDef, int, error) {
+	opts := introspectOptionsFromConfig(cfg)
+	if cfg.SchemaCacheMaxAge <= 0 || cfg.DBType == dbTypeCSV {
+		return introspectSchema(ctx, db, cfg.DBType, opts)
+	}
+
+	signature, err := schemaSignature(ctx, db, cfg.DBType)
+	if err != nil && cfg.Verbose {
+		fmt.Fprintf(os.Stderr, "warning: schema signature unavailable, relying on cache age only: %v\n", err)
+	}
+
+	path := schemaCachePath(cfg, opts)
+	if !cfg.RefreshSchema {
+		if entry, ok := readSchemaCache(path); ok {
+			fresh := time.Since(entry.CreatedAt) <= cfg.SchemaCacheMaxAge
+			unchanged := signature == "" || entry.Signature == signature
+			if fresh && unchanged {
+				if cfg.Verbose {
+					fmt.Fprintf(os.Stderr, "Using cached schema from %s\n", entry.CreatedAt.Local().Format(time.RFC3339))
+				}
+				return entry.Tables, max(entry.Total, len(entry.Tables)), nil
+			}
+			if fresh && cfg.Verbose {
+				fmt.Fprintln(os.Stderr, "Schema changed since it was cached; refreshing")
+			}
+		}
+	}
+
+	tables, total, err := introspectSchema(ctx, db, cfg.DBType, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entry := schemaCacheEntry{CreatedAt: time.Now().UTC(), Signature: signature, Tables: tables, Total: total}
+	if err := writeSchemaCache(path, entry); err != nil && cfg.Verbose {
+		fmt.Fprintf(os.Stderr, "warning: failed to write schema cache: %v\n", err)
+	}
+	return tables, total, nil
+}
+
+func schemaSignature(ctx context.Context, db *sql.DB, dbType string) (string, error) {
+	var query string
+	switch dbType {
+	case "sqlite":
+		query = `PRAGMA schema_version`
+	case "postgres":
+		query = `
+			SELECT (SELECT COALESCE(MAX(oid::bigint), 0) FROM pg_class)::text || ':' ||
+			       (SELECT COUNT(*) FROM information_schema.columns
+			        WHERE table_schema NOT IN ('pg_catalog', 'information_schema'))::text`
+	case "mysql":
+		query = `
+			SELECT CONCAT(COUNT(*), ':', COALESCE(SUM(CRC32(CONCAT(table_name, '.', column_name, ':', column_type))), 0))
+			FROM information_schema.columns
+			WHERE table_schema = DATABASE()`
+	default:
+		return "", fmt.Errorf("unsupported db type %q", dbType)
+	}
+
+	var signature string
+	if err := db.QueryRowContext(ctx, query).Scan(&signature); err != nil {
+		return "", err
+	}
+	return signature, nil
+}
+
+func schemaCachePath(cfg Config, opts introspectOptions) string {
+	h := sha256.New()
+	for _, part := range []string{
+		cfg.DBType,
+		cfg.DBURL,
+		strings.Join(opts.Tables, ","),
+		strconv.Itoa(opts.MaxTables),
+		strconv.FormatBool(opts.IncludeViews),
+		strconv.FormatBool(opts.Constraints),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	// Only hashed when set so existing cache files keep their names.
+	if opts.SystemSchemas {
+		h.Write([]byte("system-schemas"))
+		h.Write([]byte{0})
+	}
+
+	dir := strings.TrimSpace(cfg.SchemaCacheDir)
+	if dir == "" {
+		dir = defaultSchemaCacheDir()
+	}
+	return filepath.Join(dir, hex.EncodeToString(h.Sum(nil))[:32]+".json")
+}
+
+func readSchemaCache(path string) (schemaCacheEntry, bool) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return schemaCacheEntry{}, false
+	}
+
+	var entry schemaCacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return schemaCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func writeSchemaCache(path string, entry schemaCacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create schema cache directory: %w", err)
+	}
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode schema cache: %w", err)
+	}
+
+	if err := os.WriteFile(path, payload, 0o600); err != nil {
+		return fmt.Errorf("write schema cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package dbquery
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCachedIntrospectSchemaInvalidatesOnDDLChange(t *testing.T) {
+	db := openTestSQLite(t, `CREATE TABLE users (id INTEGER PRIMARY KEY)`)
+	ctx := context.Background()
+	cfg := Config{
+		DBType:            "sqlite",
+		DBURL:             "test.db",
+		SchemaMaxTables:   10,
+		SchemaCacheDir:    t.TempDir(),
+		SchemaCacheMaxAge: time.Hour,
+	}
+
+	tables, _, err := cachedIntrospectSchema(ctx, db, cfg)
+	if err != nil {
+		t.Fatalf("cachedIntrospectSchema returned error: %v", err)
+	}
+	if len(tables) != 1 || len(tables[0].Columns) != 1 {
+		t.Fatalf("unexpected initial tables: %+v", tables)
+	}
+
+	path := schemaCachePath(cfg, introspectOptionsFromConfig(cfg))
+	entry, ok := readSchemaCache(path)
+	if !ok || entry.Signature == "" {
+		t.Fatalf("expected schema cache with signature at %s, got %+v", path, entry)
+	}
+
+	entry.Tables = []tableDef{{Name: "cached_only", Kind: relationTable}}
+	if err := writeSchemaCache(path, entry); err != nil {
+		t.Fatalf("writeSchemaCache returned error: %v", err)
+	}
+	tables, _, err = cachedIntrospectSchema(ctx, db, cfg)
+	if err != nil {
+		t.Fatalf("cachedIntrospectSchema returned error: %v", err)
+	}
+	if len(tables) != 1 || tables[0].Name != "cached_only" {
+		t.Fatalf("expected cache hit, got %+v", tables)
+	}
+
+	if _, err := db.Exec(`ALTER TABLE users ADD COLUMN email TEXT`); err != nil {
+		t.Fatalf("alter table: %v", err)
+	}
+	tables, _, err = cachedIntrospectSchema(ctx, db, cfg)
+	if err != nil {
+		t.Fatalf("cachedIntrospectSchema returned error: %v", err)
+	}
+	if len(tables) != 1 || tables[0].Name != "users" || len(tables[0].Columns) != 2 {
+		t.Fatalf("expected DDL change to refresh cache, got %+v", tables)
+	}
+
+	entry, _ = readSchemaCache(path)
+	entry.Tables = []tableDef{{Name: "cached_only", Kind: relationTable}}
+	entry.CreatedAt = time.Now().Add(-2 * time.Hour)
+	if err := writeSchemaCache(path, entry); err != nil {
+		t.Fatalf("writeSchemaCache returned error: %v", err)
+	}
+	tables, _, err = cachedIntrospectSchema(ctx, db, cfg)
+	if err != nil {
+		t.Fatalf("cachedIntrospectSchema returned error: %v", err)
+	}
+	if tables[0].Name != "users" {
+		t.Fatalf("expected expired cache to be refreshed, got %+v", tables)
+	}
+}
+
+func TestSchemaCachePathSystemSchemas(t *testing.T) {
+	cfg := Config{DBType: "postgres", DBURL: "postgres://localhost/app", SchemaMaxTables: 10, SchemaCacheDir: t.TempDir()}
+	base := schemaCachePath(cfg, introspectOptionsFromConfig(cfg))
+
+	cfg.SystemSchemas = true
+	opts := introspectOptionsFromConfig(cfg)
+	if !opts.SystemSchemas {
+		t.Fatal("expected --include-system-schemas to reach the introspect options")
+	}
+	if got := schemaCachePath(cfg, opts); got == base {
+		t.Fatalf("expected a separate cache file when system schemas are included, got %s for both", got)
+	}
+}
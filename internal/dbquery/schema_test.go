@@ -3,6 +3,7 @@ package dbquery
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"testing"
 )
 
@@ -22,7 +23,7 @@ func TestIntrospectSQLite(t *testing.T) {
 		t.Fatalf("create orders table: %v", err)
 	}
 
-	tables, err := introspectSchema(ctx, db, "sqlite", nil, 10)
+	tables, _, err := introspectSchema(ctx, db, "sqlite", introspectOptions{MaxTables: 10})
 	if err != nil {
 		t.Fatalf("introspectSchema returned error: %v", err)
 	}
@@ -30,7 +31,7 @@ func TestIntrospectSQLite(t *testing.T) {
 		t.Fatalf("expected 2 tables, got %d", len(tables))
 	}
 
-	scoped, err := introspectSchema(ctx, db, "sqlite", []string{"users"}, 10)
+	scoped, _, err := introspectSchema(ctx, db, "sqlite", introspectOptions{Tables: []string{"users"}, MaxTables: 10})
 	if err != nil {
 		t.Fatalf("introspectSchema with scope returned error: %v", err)
 	}
@@ -44,3 +45,111 @@ func TestIntrospectSQLite(t *testing.T) {
 		t.Fatal("expected users table to include columns")
 	}
 }
+
+func TestFormatSchemaTablesTokenBudget(t *testing.T) {
+	tables := []tableDef{
+		{Name: "users", Columns: []string{"id INTEGER", "email TEXT", "created_at TEXT"}},
+		{Name: "orders", Columns: []string{"id INTEGER", "user_id INTEGER", "total REAL"}},
+		{Name: "payments", Columns: []string{"id INTEGER", "order_id INTEGER", "amount REAL"}},
+	}
+
+	full, omitted := formatSchemaTables(tables, 0)
+	if omitted != 0 {
+		t.Fatalf("expected no omitted tables without budget, got %d", omitted)
+	}
+	if !strings.Contains(full, "- payments (") {
+		t.Fatalf("expected all tables without budget: %s", full)
+	}
+
+	budget := estimateTokens(formatTableLine(tables[0], tables[0].Columns)) + 5
+	trimmed, omitted := formatSchemaTables(tables, budget)
+	if !strings.Contains(trimmed, "- users (") {
+		t.Fatalf("expected first table to fit budget: %s", trimmed)
+	}
+	if strings.Contains(trimmed, "- payments") {
+		t.Fatalf("expected last table to be omitted: %s", trimmed)
+	}
+	if omitted < 1 {
+		t.Fatalf("expected omitted tables, got %d", omitted)
+	}
+	if estimateTokens(trimmed) > budget {
+		t.Fatalf("trimmed schema exceeds budget %d: %q", budget, trimmed)
+	}
+}
+
+func TestIntrospectSQLiteViews(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, active INTEGER)`,
+		`CREATE VIEW active_users AS SELECT id FROM users WHERE active = 1`,
+	)
+	ctx := context.Background()
+
+	tables, _, err := introspectSchema(ctx, db, "sqlite", introspectOptions{MaxTables: 10, IncludeViews: true})
+	if err != nil {
+		t.Fatalf("introspectSchema with views returned error: %v", err)
+	}
+	if len(tables) != 2 || tables[0].Name != "active_users" || tables[0].Kind != relationView {
+		t.Fatalf("expected active_users view to be introspected, got %+v", tables)
+	}
+	if line := formatTableLine(tables[0], tables[0].Columns); !strings.HasPrefix(line, "- active_users [view] (id") {
+		t.Fatalf("unexpected view schema line: %q", line)
+	}
+
+	tables, _, err = introspectSchema(ctx, db, "sqlite", introspectOptions{MaxTables: 10})
+	if err != nil {
+		t.Fatalf("introspectSchema without views returned error: %v", err)
+	}
+	if len(tables) != 1 || tables[0].Name != "users" {
+		t.Fatalf("expected views to be excluded, got %+v", tables)
+	}
+}
+
+func TestIntrospectSchemaReportsTotalWhenTruncated(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE a (id INTEGER)`,
+		`CREATE TABLE b (id INTEGER)`,
+		`CREATE TABLE c (id INTEGER)`,
+	)
+
+	tables, total, err := introspectSchema(context.Background(), db, "sqlite", introspectOptions{MaxTables: 2})
+	if err != nil {
+		t.Fatalf("introspectSchema returned error: %v", err)
+	}
+	if len(tables) != 2 || total != 3 {
+		t.Fatalf("expected 2 of 3 tables, got %d of %d", len(tables), total)
+	}
+}
+
+func TestIntrospectSQLiteConstraints(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE accounts (
+			id INTEGER PRIMARY KEY,
+			status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'inactive')),
+			note TEXT DEFAULT 'check (x)',
+			balance REAL DEFAULT 0,
+			CONSTRAINT positive CHECK (balance >= 0)
+		)`,
+	)
+	ctx := context.Background()
+
+	tables, _, err := introspectSchema(ctx, db, "sqlite", introspectOptions{MaxTables: 10})
+	if err != nil {
+		t.Fatalf("introspectSchema returned error: %v", err)
+	}
+	if line := formatTableLine(tables[0], tables[0].Columns); strings.Contains(line, "DEFAULT") || strings.Contains(line, "CHECK") {
+		t.Fatalf("constraints should be omitted by default, got %q", line)
+	}
+	if !strings.Contains(tables[0].Columns[1], "status TEXT NOT NULL") || strings.Contains(tables[0].Columns[2], "NOT NULL") {
+		t.Fatalf("expected nullability in column descriptions, got %v", tables[0].Columns)
+	}
+
+	tables, _, err = introspectSchema(ctx, db, "sqlite", introspectOptions{MaxTables: 10, Constraints: true})
+	if err != nil {
+		t.Fatalf("introspectSchema returned error: %v", err)
+	}
+	line := formatTableLine(tables[0], tables[0].Columns)
+	want := "- accounts (id INTEGER, status TEXT NOT NULL DEFAULT 'active', note TEXT DEFAULT 'check (x)', balance REAL DEFAULT 0) CHECK (status IN ('active', 'inactive')) CHECK (balance >= 0)\n"
+	if line != want {
+		t.Fatalf("unexpected schema line:\n got %q\nwant %q", line, want)
+	}
+}
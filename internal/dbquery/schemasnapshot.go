@@ -0,0 +1,191 @@
+package dbquery
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type schemaSnapshot struct {
+	DBType    string                 `json:"db_type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Options   *schemaSnapshotOptions `json:"options,omitempty"`
+	Tables    []tableDef             `json:"tables"`
+}
+
+type schemaSnapshotOptions struct {
+	Tables        []string `json:"tables,omitempty"`
+	IncludeViews  bool     `json:"include_views"`
+	Constraints   bool     `json:"constraints"`
+	SystemSchemas bool     `json:"system_schemas,omitempty"`
+}
+
+func runSchemaSnapshot(cfg Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	db, schemaDB, err := openQueryDatabases(ctx, cfg)
+	if err != nil {
+		return wrapError(ErrDBConnect, fmt.Errorf("open database: %w", err))
+	}
+	defer closeDatabases(db, schemaDB)
+
+	opts := snapshotIntrospectOptions(cfg)
+	tables, _, err := introspectSchema(ctx, schemaDB, cfg.DBType, opts)
+	if err != nil {
+		return wrapError(ErrQuery, fmt.Errorf("introspect schema: %w", err))
+	}
+	snapshot := schemaSnapshot{
+		DBType:    cfg.DBType,
+		CreatedAt: time.Now().UTC(),
+		Options: &schemaSnapshotOptions{
+			Tables:        opts.Tables,
+			IncludeViews:  opts.IncludeViews,
+			Constraints:   opts.Constraints,
+			SystemSchemas: opts.SystemSchemas,
+		},
+		Tables: tables,
+	}
+	if err := writeSchemaSnapshot(cfg.SnapshotFile, snapshot); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Saved schema snapshot of %d %s to %s\n", len(tables), pluralize(len(tables), "relation", "relations"), cfg.SnapshotFile)
+	return nil
+}
+
+// Snapshots cover every matching relation so --schema-max-tables truncation is not reported as drift.
+func snapshotIntrospectOptions(cfg Config) introspectOptions {
+	opts := introspectOptionsFromConfig(cfg)
+	opts.MaxTables = math.MaxInt
+	return opts
+}
+
+// The check re-introspects the way the snapshot was taken, so this run's --tables or
+// --schema-constraints do not show up as drift.
+func verifyIntrospectOptions(cfg Config, snapshot schemaSnapshot) introspectOptions {
+	opts := snapshotIntrospectOptions(cfg)
+	if o := snapshot.Options; o != nil {
+		opts.Tables = o.Tables
+		opts.IncludeViews = o.IncludeViews
+		opts.Constraints = o.Constraints
+		opts.SystemSchemas = o.SystemSchemas
+	}
+	return opts
+}
+
+func writeSchemaSnapshot(path string, snapshot schemaSnapshot) error {
+	payload, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode schema snapshot: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create schema snapshot directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, append(payload, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write schema snapshot: %w", err)
+	}
+	return nil
+}
+
+func readSchemaSnapshot(path string) (schemaSnapshot, error) {
+	var snapshot schemaSnapshot
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, fmt.Errorf("read schema snapshot: %w", err)
+	}
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("parse schema snapshot %s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+func verifySchemaSnapshot(ctx context.Context, db *sql.DB, cfg Config) error {
+	snapshot, err := readSchemaSnapshot(cfg.VerifySchema)
+	if err != nil {
+		return wrapError(ErrConfig, err)
+	}
+	live, _, err := introspectSchema(ctx, db, cfg.DBType, verifyIntrospectOptions(cfg, snapshot))
+	if err != nil {
+		return wrapError(ErrQuery, fmt.Errorf("introspect schema: %w", err))
+	}
+
+	drift := diffSchemaSnapshot(snapshot.Tables, live)
+	if len(drift) == 0 {
+		if cfg.Verbose {
+			fmt.Fprintf(os.Stderr, "Schema matches snapshot %s\n", cfg.VerifySchema)
+		}
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "warning: live schema differs from snapshot %s (taken %s):\n", cfg.VerifySchema, snapshot.CreatedAt.Local().Format(time.RFC3339))
+	for _, d := range drift {
+		fmt.Fprintf(os.Stderr, "  %s\n", d)
+	}
+	return nil
+}
+
+func diffSchemaSnapshot(snapshot, live []tableDef) []string {
+	liveByName := make(map[string]tableDef, len(live))
+	for _, t := range live {
+		liveByName[strings.ToLower(t.Name)] = t
+	}
+	snapshotNames := make(map[string]struct{}, len(snapshot))
+
+	var drift []string
+	for _, want := range snapshot {
+		key := strings.ToLower(want.Name)
+		snapshotNames[key] = struct{}{}
+		got, ok := liveByName[key]
+		if !ok {
+			drift = append(drift, "- table "+want.Name+" removed")
+			continue
+		}
+		drift = append(drift, diffSnapshotColumns(want, got)...)
+	}
+	for _, t := range live {
+		if _, ok := snapshotNames[strings.ToLower(t.Name)]; !ok {
+			drift = append(drift, "+ table "+t.Name+" added")
+		}
+	}
+	return drift
+}
+
+func diffSnapshotColumns(want, got tableDef) []string {
+	gotByName := make(map[string]string, len(got.Columns))
+	for _, c := range got.Columns {
+		gotByName[snapshotColumnName(c)] = c
+	}
+	wantNames := make(map[string]struct{}, len(want.Columns))
+
+	var drift []string
+	for _, c := range want.Columns {
+		name := snapshotColumnName(c)
+		wantNames[name] = struct{}{}
+		live, ok := gotByName[name]
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("- column %s.%s removed", want.Name, name))
+		case live != c:
+			drift = append(drift, fmt.Sprintf("~ column %s.%s changed: %s -> %s", want.Name, name, c, live))
+		}
+	}
+	for _, c := range got.Columns {
+		name := snapshotColumnName(c)
+		if _, ok := wantNames[name]; !ok {
+			drift = append(drift, fmt.Sprintf("+ column %s.%s added", got.Name, name))
+		}
+	}
+	return drift
+}
+
+func snapshotColumnName(column string) string {
+	name, _, _ := strings.Cut(column, " ")
+	return strings.ToLower(name)
+}
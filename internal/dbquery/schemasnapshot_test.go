@@ -0,0 +1,119 @@
+package dbquery
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaSnapshotDrift(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, legacy_flag INTEGER)`,
+		`CREATE TABLE audit (id INTEGER PRIMARY KEY)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("exec %q: %v", stmt, err)
+		}
+	}
+
+	snapshotPath := filepath.Join(dir, "schema", "schema.json")
+	cfg, err := parseConfig(append([]string{"schema", "snapshot"}, testQueryArgs(t, "--db-url", dbPath, "--file", snapshotPath, "--schema-max-tables", "1")...))
+	if err != nil {
+		t.Fatalf("parseConfig returned error: %v", err)
+	}
+	if cfg.Mode != modeSchema || cfg.SnapshotFile != snapshotPath {
+		t.Fatalf("unexpected schema snapshot config: mode=%q file=%q", cfg.Mode, cfg.SnapshotFile)
+	}
+	if err := runSchemaSnapshot(cfg); err != nil {
+		t.Fatalf("runSchemaSnapshot returned error: %v", err)
+	}
+	snapshot, err := readSchemaSnapshot(snapshotPath)
+	if err != nil || len(snapshot.Tables) != 2 {
+		t.Fatalf("expected both tables in the snapshot despite --schema-max-tables, got %+v (%v)", snapshot.Tables, err)
+	}
+
+	for _, stmt := range []string{
+		`DROP TABLE audit`,
+		`CREATE TABLE events (id INTEGER PRIMARY KEY)`,
+		`ALTER TABLE users ADD COLUMN created_at TEXT`,
+		`ALTER TABLE users DROP COLUMN legacy_flag`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("exec %q: %v", stmt, err)
+		}
+	}
+	live, _, err := introspectSchema(context.Background(), db, "sqlite", snapshotIntrospectOptions(cfg))
+	if err != nil {
+		t.Fatalf("introspectSchema returned error: %v", err)
+	}
+	want := []string{
+		"- table audit removed",
+		"- column users.legacy_flag removed",
+		"+ column users.created_at added",
+		"+ table events added",
+	}
+	if got := diffSchemaSnapshot(snapshot.Tables, live); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected drift:\n got %q\nwant %q", got, want)
+	}
+
+	if _, err := parseConfig(append([]string{"schema", "snapshot"}, testQueryArgs(t, "--db-url", dbPath)...)); err == nil {
+		t.Fatal("expected schema snapshot without --file to be rejected")
+	}
+}
+
+func TestVerifySchemaSnapshotUsesSnapshotOptions(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, status TEXT NOT NULL DEFAULT 'active')`,
+		`CREATE TABLE audit (id INTEGER PRIMARY KEY)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("exec %q: %v", stmt, err)
+		}
+	}
+
+	snapshotPath := filepath.Join(dir, "schema.json")
+	cfg, err := parseConfig(append([]string{"schema", "snapshot"}, testQueryArgs(t, "--db-url", dbPath, "--file", snapshotPath)...))
+	if err != nil {
+		t.Fatalf("parseConfig returned error: %v", err)
+	}
+	if err := runSchemaSnapshot(cfg); err != nil {
+		t.Fatalf("runSchemaSnapshot returned error: %v", err)
+	}
+	raw, err := os.ReadFile(snapshotPath)
+	if err != nil || !strings.Contains(string(raw), `"name": "users"`) || !strings.Contains(string(raw), `"options"`) {
+		t.Fatalf("expected snake_case table fields and stored options in the snapshot, got %s (%v)", raw, err)
+	}
+	snapshot, err := readSchemaSnapshot(snapshotPath)
+	if err != nil {
+		t.Fatalf("readSchemaSnapshot returned error: %v", err)
+	}
+
+	runCfg := cfg
+	runCfg.Tables = []string{"users"}
+	runCfg.SchemaConstraints = true
+	live, _, err := introspectSchema(context.Background(), db, "sqlite", verifyIntrospectOptions(runCfg, snapshot))
+	if err != nil {
+		t.Fatalf("introspectSchema returned error: %v", err)
+	}
+	if drift := diffSchemaSnapshot(snapshot.Tables, live); len(drift) != 0 {
+		t.Fatalf("this run's --tables and --schema-constraints should not be reported as drift: %q", drift)
+	}
+}
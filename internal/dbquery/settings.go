@@ -6,13 +6,33 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
+var llmProviderNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 type Settings struct {
-	APIKey string `json:"api_key,omitempty"`
-	DBType string `json:"db_type,omitempty"`
-	DBURL  string `json:"db_url,omitempty"`
+	APIKey string            `json:"api_key,omitempty"`
+	Keys   map[string]string `json:"keys,omitempty"`
+	DBType string            `json:"db_type,omitempty"`
+	DBURL  string            `json:"db_url,omitempty"`
+
+	OpenAIOrg     string `json:"openai_org,omitempty"`
+	OpenAIProject string `json:"openai_project,omitempty"`
+
+	ReadURL string `json:"read_url,omitempty"`
+}
+
+func (s Settings) apiKeyFor(provider string) string {
+	provider = normalizeLLMProvider(provider)
+	if key := strings.TrimSpace(s.Keys[provider]); key != "" {
+		return key
+	}
+	if provider == providerOpenAI {
+		return strings.TrimSpace(s.APIKey)
+	}
+	return ""
 }
 
 func runSet(cfg Config) error {
@@ -23,12 +43,41 @@ func runSet(cfg Config) error {
 
 	switch cfg.SetTarget {
 	case "llm-key":
-		settings.APIKey = cfg.SetLLMKey
+		if cfg.SetLLMProvider == "" {
+			settings.APIKey = cfg.SetLLMKey
+		} else {
+			if settings.Keys == nil {
+				settings.Keys = map[string]string{}
+			}
+			settings.Keys[cfg.SetLLMProvider] = cfg.SetLLMKey
+		}
 		if err := saveSettings(cfg.SettingsFile, settings); err != nil {
 			return err
 		}
+		if cfg.SetLLMProvider != "" {
+			fmt.Printf("Saved %s LLM key to %s\n", cfg.SetLLMProvider, cfg.SettingsFile)
+			return nil
+		}
 		fmt.Printf("Saved default LLM key to %s\n", cfg.SettingsFile)
 		return nil
+	case "openai-org", "openai-project":
+		if cfg.SetTarget == "openai-org" {
+			settings.OpenAIOrg = cfg.SetValue
+		} else {
+			settings.OpenAIProject = cfg.SetValue
+		}
+		if err := saveSettings(cfg.SettingsFile, settings); err != nil {
+			return err
+		}
+		fmt.Printf("Saved default %s to %s\n", cfg.SetTarget, cfg.SettingsFile)
+		return nil
+	case "read-url":
+		settings.ReadURL = cfg.SetValue
+		if err := saveSettings(cfg.SettingsFile, settings); err != nil {
+			return err
+		}
+		fmt.Printf("Saved default read-url to %s\n", cfg.SettingsFile)
+		return nil
 	case "db":
 		settings.DBType = cfg.SetDBType
 		settings.DBURL = cfg.SetDBURL
@@ -69,7 +118,13 @@ func loadSettings(path string) (Settings, error) {
 	}
 
 	s.APIKey = strings.TrimSpace(s.APIKey)
+	for provider, key := range s.Keys {
+		s.Keys[provider] = strings.TrimSpace(key)
+	}
 	s.DBURL = strings.TrimSpace(s.DBURL)
+	s.OpenAIOrg = strings.TrimSpace(s.OpenAIOrg)
+	s.ReadURL = strings.TrimSpace(s.ReadURL)
+	s.OpenAIProject = strings.TrimSpace(s.OpenAIProject)
 
 	return s, nil
 }
@@ -99,14 +154,34 @@ func saveSettings(path string, s Settings) error {
 	return nil
 }
 
+// The saved db_type describes the saved db_url; a URL given on the command line is detected on its own
+// and only falls back to the saved type when detection fails.
+func settingsDBTypeApplies(dbURL, savedURL string) bool {
+	dbURL = strings.TrimSpace(dbURL)
+	if dbURL == "" || dbURL == strings.TrimSpace(savedURL) {
+		return true
+	}
+	_, err := detectDBTypeFromLocation(dbURL)
+	return err != nil
+}
+
 func applySettingsDefaults(cfg *Config, s Settings) {
-	if strings.TrimSpace(cfg.APIKey) == "" && strings.TrimSpace(s.APIKey) != "" {
-		cfg.APIKey = strings.TrimSpace(s.APIKey)
+	if strings.TrimSpace(cfg.APIKey) == "" {
+		cfg.APIKey = s.apiKeyFor(cfg.LLMProvider)
 	}
-	if strings.TrimSpace(cfg.DBType) == "" && strings.TrimSpace(s.DBType) != "" {
+	if strings.TrimSpace(cfg.DBType) == "" && strings.TrimSpace(s.DBType) != "" && settingsDBTypeApplies(cfg.DBURL, s.DBURL) {
 		cfg.DBType = strings.TrimSpace(s.DBType)
 	}
 	if strings.TrimSpace(cfg.DBURL) == "" && strings.TrimSpace(s.DBURL) != "" {
 		cfg.DBURL = strings.TrimSpace(s.DBURL)
 	}
+	if strings.TrimSpace(cfg.ReadURL) == "" && strings.TrimSpace(cfg.DBURL) == strings.TrimSpace(s.DBURL) {
+		cfg.ReadURL = strings.TrimSpace(s.ReadURL)
+	}
+	if strings.TrimSpace(cfg.OpenAIOrg) == "" {
+		cfg.OpenAIOrg = strings.TrimSpace(s.OpenAIOrg)
+	}
+	if strings.TrimSpace(cfg.OpenAIProject) == "" {
+		cfg.OpenAIProject = strings.TrimSpace(s.OpenAIProject)
+	}
 }
@@ -29,6 +29,9 @@ func runShow(cfg Config) error {
 			return err
 		}
 		settings.APIKey = maskSecret(settings.APIKey)
+		for provider, key := range settings.Keys {
+			settings.Keys[provider] = maskSecret(key)
+		}
 		payload.SettingsFile = cfg.SettingsFile
 		payload.Settings = &settings
 	}
@@ -45,6 +48,11 @@ func runShow(cfg Config) error {
 		}
 	}
 
+	if cfg.ShowOutput == "table" {
+		fmt.Print(renderShowTables(payload))
+		return nil
+	}
+
 	data, err := json.MarshalIndent(payload, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal show output: %w", err)
@@ -54,6 +62,64 @@ func runShow(cfg Config) error {
 	return nil
 }
 
+func renderShowTables(payload showPayload) string {
+	opts := renderOptions{TableStyle: tableStyleBox}
+	var b strings.Builder
+
+	if payload.Settings != nil {
+		s := payload.Settings
+		rows := make([]map[string]any, 0)
+		add := func(key, value string) {
+			if value != "" {
+				rows = append(rows, map[string]any{"setting": key, "value": value})
+			}
+		}
+		add("api_key", s.APIKey)
+		providers := make([]string, 0, len(s.Keys))
+		for provider := range s.Keys {
+			providers = append(providers, provider)
+		}
+		sort.Strings(providers)
+		for _, provider := range providers {
+			add("keys."+provider, s.Keys[provider])
+		}
+		add("db_type", s.DBType)
+		add("db_url", s.DBURL)
+		add("read_url", s.ReadURL)
+		add("openai_org", s.OpenAIOrg)
+		add("openai_project", s.OpenAIProject)
+
+		fmt.Fprintf(&b, "Settings (%s):\n", payload.SettingsFile)
+		if len(rows) == 0 {
+			b.WriteString("(none)\n")
+		} else {
+			b.WriteString(renderTable([]string{"setting", "value"}, rows, opts) + "\n")
+		}
+	}
+
+	if payload.ProfilesFile != "" {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		rows := make([]map[string]any, 0, len(payload.Profiles))
+		for _, np := range payload.Profiles {
+			row := map[string]any{"profile": np.Name, "db_type": np.Profile.DBType, "model": np.Profile.Model, "limit": nil}
+			if np.Profile.Limit > 0 {
+				row["limit"] = np.Profile.Limit
+			}
+			rows = append(rows, row)
+		}
+
+		fmt.Fprintf(&b, "Profiles (%s):\n", payload.ProfilesFile)
+		if len(rows) == 0 {
+			b.WriteString("(none)\n")
+		} else {
+			b.WriteString(renderTable([]string{"profile", "db_type", "model", "limit"}, rows, opts) + "\n")
+		}
+	}
+	return b.String()
+}
+
 func maskSecret(v string) string {
 	s := strings.TrimSpace(v)
 	if s == "" {
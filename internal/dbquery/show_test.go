@@ -1,6 +1,9 @@
 package dbquery
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestParseShowConfig(t *testing.T) {
 	cfg, err := parseShowConfig(nil)
@@ -26,6 +29,21 @@ func TestParseShowConfig(t *testing.T) {
 	if cfg.ShowTarget != "profiles" {
 		t.Fatalf("expected profiles target, got %q", cfg.ShowTarget)
 	}
+	if cfg.ShowOutput != "json" {
+		t.Fatalf("expected default output json, got %q", cfg.ShowOutput)
+	}
+
+	cfg, err = parseShowConfig([]string{"profiles", "--output", "TABLE"})
+	if err != nil {
+		t.Fatalf("parseShowConfig --output table returned error: %v", err)
+	}
+	if cfg.ShowOutput != "table" {
+		t.Fatalf("expected table output, got %q", cfg.ShowOutput)
+	}
+
+	if _, err := parseShowConfig([]string{"--output", "yaml"}); err == nil {
+		t.Fatal("expected error for unsupported --output")
+	}
 }
 
 func TestMaskSecret(t *testing.T) {
@@ -57,3 +75,38 @@ func TestToNamedProfilesSorted(t *testing.T) {
 		t.Fatalf("profiles are not sorted: %+v", out)
 	}
 }
+
+func TestRenderShowTables(t *testing.T) {
+	payload := showPayload{
+		Target:       "all",
+		SettingsFile: "/tmp/settings.json",
+		ProfilesFile: "/tmp/profiles.json",
+		Settings:     &Settings{APIKey: maskSecret("sk_1234567890"), DBType: "postgres"},
+		Profiles: []namedProfile{
+			{Name: "analytics", Profile: Profile{DBType: "postgres", Model: "gpt-4o-mini", Limit: 50}},
+			{Name: "local", Profile: Profile{DBType: "sqlite"}},
+		},
+	}
+
+	out := renderShowTables(payload)
+	for _, want := range []string{
+		"Settings (/tmp/settings.json):",
+		"Profiles (/tmp/profiles.json):",
+		"sk_1*****7890",
+		"analytics",
+		"gpt-4o-mini",
+		"50",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "db_url") {
+		t.Fatalf("expected empty settings to be omitted, got:\n%s", out)
+	}
+
+	out = renderShowTables(showPayload{Target: "profiles", ProfilesFile: "/tmp/profiles.json"})
+	if strings.Contains(out, "Settings") || !strings.Contains(out, "(none)") {
+		t.Fatalf("unexpected output for empty profiles: %q", out)
+	}
+}
@@ -1,30 +1,387 @@
 package dbquery
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-var forbiddenWritePattern = regexp.MustCompile(`(?i)\b(insert|update|delete|drop|alter|truncate|create|grant|revoke|merge|call|replace)\b`)
+var defaultForbiddenKeywords = []string{"insert", "update", "delete", "drop", "alter", "truncate", "create", "grant", "revoke", "merge", "call", "replace", "copy"}
+var forbiddenWritePattern = keywordPattern(defaultForbiddenKeywords)
+var serverFileAccessPattern = regexp.MustCompile(`(?i)\b(load\s+data)\b|\bcopy\b[^;]*?\b((?:from|to)\s+program)\b`)
+var keywordNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
 var hasLimitPattern = regexp.MustCompile(`(?i)\blimit\s+\d+`)
+var trailingLimitPattern = regexp.MustCompile(`(?i)\blimit\s+(\d+)(?:\s+offset\s+\d+)?\s*;?\s*$`)
+var hasFetchPattern = regexp.MustCompile(`(?i)\bfetch\s+(?:first|next)\s+\d+`)
+var trailingFetchPattern = regexp.MustCompile(`(?i)\bfetch\s+(?:first|next)\s+(\d+)\s+rows?\s+only\s*;?\s*$`)
+var hasTopPattern = regexp.MustCompile(`(?i)\bselect\s+(?:(?:distinct|all)\s+)?top\s*\(?\s*\d+`)
+var leadingTopPattern = regexp.MustCompile(`(?i)^select\s+(?:(?:distinct|all)\s+)?top\s*\(?\s*(\d+)`)
+var selectHeadPattern = regexp.MustCompile(`(?i)^select\s+(?:(?:distinct|all)\s+)?`)
+var returningPattern = regexp.MustCompile(`(?i)\breturning\b`)
+var multiRowClausePattern = regexp.MustCompile(`(?i)\b(group\s+by|union|intersect|except|distinct|over|generate_series|unnest|json_each|json_tree)\b`)
+var aggregateCallPattern = regexp.MustCompile(`(?i)^(count|sum|avg|min|max|total|group_concat|string_agg|array_agg|json_agg|jsonb_agg|bool_and|bool_or|every|stddev|variance)\s*\(`)
+var writableCTEPattern = regexp.MustCompile(`(?i)\bas\s*(not\s+)?(materialized\s*)?\(\s*(insert|update|delete|merge)\b`)
+var tableReferencePattern = regexp.MustCompile(`(?i)\b(?:from|join|into|update)\s+((?:"[^"]+"|` + "`[^`]+`" + `|[a-z_][a-z0-9_$]*)(?:\.(?:"[^"]+"|` + "`[^`]+`" + `|[a-z_][a-z0-9_$]*))?)`)
+var cteNamePattern = regexp.MustCompile(`(?i)(?:\bwith(?:\s+recursive)?|,)\s*("[^"]+"|[a-z_][a-z0-9_]*)\s*(?:\([^)]*\)\s*)?as\s*(?:not\s+)?(?:materialized\s*)?\(`)
+var writeTargetPattern = regexp.MustCompile(`(?i)(\w+\s+)?\b(?:insert\s+(?:ignore\s+)?into|replace\s+into|merge\s+into|update(?:\s+only)?|delete\s+from(?:\s+only)?|truncate(?:\s+table)?(?:\s+only)?|(?:create|drop|alter)\s+(?:(?:temporary|temp|unlogged)\s+)?table(?:\s+if\s+(?:not\s+)?exists)?)\s+((?:"[^"]+"|` + "`[^`]+`" + `|[a-z_][a-z0-9_$]*)(?:\.(?:"[^"]+"|` + "`[^`]+`" + `|[a-z_][a-z0-9_$]*))?)`)
+var selectIntoPattern = regexp.MustCompile(`(?i)(\w+\s+)?\binto\s+(?:(?:temporary|temp|unlogged)\s+)?(?:table\s+)?((?:"[^"]+"|` + "`[^`]+`" + `|@?[a-z_][a-z0-9_$]*)(?:\.(?:"[^"]+"|` + "`[^`]+`" + `|[a-z_][a-z0-9_$]*))?)`)
+var selectAliasPattern = regexp.MustCompile(`(?i)\s+(as\s+)?("[^"]*"|` + "`[^`]*`" + `|[a-z_][a-z0-9_]*)$`)
+
+const dialectSQLServer = "sqlserver"
+
+var readStatementKeywords = map[string]struct{}{
+	"select":   {},
+	"with":     {},
+	"explain":  {},
+	"show":     {},
+	"describe": {},
+	"desc":     {},
+	"pragma":   {},
+	"values":   {},
+	"table":    {},
+}
+
+var writeStatementKeywords = map[string]struct{}{
+	"insert":   {},
+	"update":   {},
+	"delete":   {},
+	"merge":    {},
+	"replace":  {},
+	"create":   {},
+	"alter":    {},
+	"drop":     {},
+	"truncate": {},
+	"grant":    {},
+	"revoke":   {},
+	"call":     {},
+	"copy":     {},
+	"load":     {},
+}
+
+func keywordPattern(keywords []string) *regexp.Regexp {
+	if len(keywords) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)\b(` + strings.Join(keywords, "|") + `)\b`)
+}
+
+func forbiddenKeywordPattern(deny, allow []string) *regexp.Regexp {
+	if len(deny) == 0 && len(allow) == 0 {
+		return forbiddenWritePattern
+	}
+	allowed := make(map[string]struct{}, len(allow))
+	for _, k := range allow {
+		allowed[strings.ToLower(k)] = struct{}{}
+	}
+	seen := make(map[string]struct{})
+	keywords := make([]string, 0, len(defaultForbiddenKeywords)+len(deny))
+	for _, k := range append(append([]string(nil), defaultForbiddenKeywords...), deny...) {
+		k = strings.ToLower(k)
+		if _, ok := allowed[k]; ok {
+			continue
+		}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		keywords = append(keywords, k)
+	}
+	return keywordPattern(keywords)
+}
+
+func validateKeywordList(flagName string, keywords []string, defaultsOnly bool) error {
+	defaults := make(map[string]struct{}, len(defaultForbiddenKeywords))
+	for _, k := range defaultForbiddenKeywords {
+		defaults[k] = struct{}{}
+	}
+	for _, k := range keywords {
+		lower := strings.ToLower(k)
+		if !keywordNamePattern.MatchString(lower) {
+			return fmt.Errorf("%s: invalid keyword %q (expected a single SQL word)", flagName, k)
+		}
+		if _, ok := defaults[lower]; defaultsOnly && !ok {
+			return fmt.Errorf("%s: %q is not a default forbidden keyword (%s)", flagName, k, strings.Join(defaultForbiddenKeywords, ", "))
+		}
+	}
+	return nil
+}
 
 func ensureReadOnlySQL(query string) error {
+	return ensureReadOnlySQLWith(query, forbiddenWritePattern)
+}
+
+func ensureReadOnlySQLWith(query string, forbidden *regexp.Regexp) error {
+	if r := classifyReadOnlySQL(query, forbidden); r != nil {
+		return wrapError(ErrReadOnlyViolation, errors.New(r.reason))
+	}
+	return nil
+}
+
+func checkReadOnlySQL(query string, forbidden *regexp.Regexp, explain bool) error {
+	r := classifyReadOnlySQL(query, forbidden)
+	if r == nil {
+		return nil
+	}
+	if explain {
+		return wrapError(ErrReadOnlyViolation, fmt.Errorf("%s\n%s", r.reason, r.detail))
+	}
+	return wrapError(ErrReadOnlyViolation, errors.New(r.reason))
+}
+
+func explainReadOnlyRejection(query string, forbidden *regexp.Regexp) string {
+	if r := classifyReadOnlySQL(query, forbidden); r != nil {
+		return r.detail
+	}
+	return ""
+}
+
+type readOnlyRejection struct {
+	reason string
+	detail string
+}
+
+// classifyReadOnlySQL is the single read-only check: reason becomes the error
+// and detail the --explain-rejection line, so the two cannot disagree.
+func classifyReadOnlySQL(query string, forbidden *regexp.Regexp) *readOnlyRejection {
 	cleaned := stripLeadingComments(query)
-	lower := strings.ToLower(strings.TrimSpace(cleaned))
+	offset := strings.LastIndex(query, cleaned)
+	lower := strings.ToLower(cleaned)
+
+	reject := func(reason, what string, start, end int) *readOnlyRejection {
+		pos := utf8.RuneCountInString(query[:offset+start]) + 1
+		return &readOnlyRejection{
+			reason: reason,
+			detail: fmt.Sprintf("blocked: %s at position %d: `%s`", what, pos, sqlFragment(cleaned, start, end)),
+		}
+	}
 
 	if !(strings.HasPrefix(lower, "select") || strings.HasPrefix(lower, "with") || strings.HasPrefix(lower, "explain select")) {
-		return fmt.Errorf("generated SQL is not read-only; use --allow-write to permit non-SELECT statements")
+		end := strings.IndexFunc(cleaned, unicode.IsSpace)
+		if end < 0 {
+			end = len(cleaned)
+		}
+		return reject("generated SQL is not read-only; use --allow-write to permit non-SELECT statements",
+			fmt.Sprintf("statement starts with '%s', not SELECT/WITH/EXPLAIN SELECT", strings.ToLower(cleaned[:end])), 0, end)
+	}
+
+	if strings.HasPrefix(lower, "with") {
+		if m := writableCTEPattern.FindStringSubmatchIndex(cleaned); m != nil {
+			keyword := strings.ToLower(cleaned[m[6]:m[7]])
+			return reject(fmt.Sprintf("generated SQL contains a data-modifying CTE (%s inside WITH); use --allow-write if intentional", strings.ToUpper(keyword)),
+				fmt.Sprintf("data-modifying CTE keyword '%s'", keyword), m[6], m[7])
+		}
+		if main := cteMainKeyword(lower); main != "" && main != "select" && main != "values" && main != "table" {
+			start, end := 0, len("with")
+			if i := indexTopLevelKeyword(cleaned[len("with"):], main); i >= 0 {
+				start = i + len("with")
+				end = start + len(main)
+			}
+			return reject(fmt.Sprintf("generated SQL runs %s after WITH; use --allow-write if intentional", strings.ToUpper(main)),
+				fmt.Sprintf("'%s' runs after WITH", main), start, end)
+		}
+	}
+
+	// Not affected by --allow-keywords: SELECT ... INTO creates a table or writes a server file.
+	if into := selectIntoClauses(cleaned); len(into) > 0 {
+		c := into[0]
+		what := fmt.Sprintf("SELECT ... INTO creates table '%s'", c.target)
+		if c.file {
+			what = fmt.Sprintf("SELECT ... INTO %s writes a server file", strings.ToUpper(c.target))
+		}
+		return reject("generated SQL writes its result with SELECT ... INTO; use --allow-write if intentional", what, c.start, c.end)
 	}
 
-	if forbiddenWritePattern.MatchString(lower) {
-		return fmt.Errorf("generated SQL contains write/DDL keywords; use --allow-write if intentional")
+	// Not affected by --allow-keywords: these read server files or run programs.
+	if m := serverFileAccessPattern.FindStringSubmatchIndex(cleaned); m != nil {
+		start, end := m[2], m[3]
+		if start < 0 {
+			start, end = m[4], m[5]
+		}
+		return reject("generated SQL reads server files or runs programs (LOAD DATA / COPY ... PROGRAM); use --allow-write if intentional",
+			fmt.Sprintf("server file/program access '%s'", strings.Join(strings.Fields(strings.ToLower(cleaned[start:end])), " ")), start, end)
 	}
 
+	if forbidden != nil {
+		if m := forbidden.FindStringSubmatchIndex(cleaned); m != nil {
+			return reject("generated SQL contains write/DDL keywords; use --allow-write if intentional",
+				fmt.Sprintf("write keyword '%s'", strings.ToLower(cleaned[m[2]:m[3]])), m[2], m[3])
+		}
+	}
+	return nil
+}
+
+func sqlFragment(s string, start, end int) string {
+	const margin = 30
+	from, to := start-margin, end+margin
+	prefix, suffix := "... ", " ..."
+	if from <= 0 {
+		from, prefix = 0, ""
+	}
+	if to >= len(s) {
+		to, suffix = len(s), ""
+	}
+	if i := strings.IndexFunc(s[from:start], unicode.IsSpace); from > 0 && i >= 0 {
+		from += i
+	}
+	if i := strings.LastIndexFunc(s[end:to], unicode.IsSpace); to < len(s) && i >= 0 {
+		to = end + i
+	}
+	for from > 0 && !utf8.RuneStart(s[from]) {
+		from--
+	}
+	for to < len(s) && !utf8.RuneStart(s[to]) {
+		to++
+	}
+	return prefix + strings.Join(strings.Fields(s[from:to]), " ") + suffix
+}
+
+func ensureWriteTablesAllowed(query string, allowed []string) error {
+	into := selectIntoClauses(stripLeadingComments(query))
+	if !isWriteStatement(query) && !writableCTEPattern.MatchString(query) && len(into) == 0 {
+		return nil
+	}
+	for _, c := range into {
+		if c.file {
+			return wrapError(ErrReadOnlyViolation, fmt.Errorf("SELECT ... INTO %s writes a server file; --allow-write-tables only permits writes to listed tables", strings.ToUpper(c.target)))
+		}
+	}
+
+	targets := writeTargetTables(query)
+	if len(targets) == 0 {
+		return wrapError(ErrReadOnlyViolation, errors.New("could not determine the target table of the write statement; --allow-write-tables only permits INSERT/UPDATE/DELETE on listed tables"))
+	}
+
+	var denied []string
+	for _, target := range targets {
+		if !tableAllowed(target, allowed) {
+			denied = append(denied, target)
+		}
+	}
+	if len(denied) > 0 {
+		return wrapError(ErrReadOnlyViolation, fmt.Errorf("SQL writes to %s not in --allow-write-tables: %s", pluralize(len(denied), "a table", "tables"), strings.Join(denied, ", ")))
+	}
 	return nil
 }
 
-func ensureLimit(query string, limit int) string {
+func writeTargetTables(query string) []string {
+	masked := maskStringLiterals(stripLeadingComments(query))
+	seen := map[string]struct{}{}
+	out := make([]string, 0)
+	for _, m := range writeTargetPattern.FindAllStringSubmatch(masked, -1) {
+		prev := strings.ToLower(strings.TrimSpace(m[1]))
+		if prev == "key" || prev == "do" || prev == "for" || strings.EqualFold(m[2], "set") {
+			continue
+		}
+		parts := strings.Split(m[2], ".")
+		for i := range parts {
+			parts[i] = unquoteIdent(parts[i])
+		}
+		name := strings.Join(parts, ".")
+		if _, ok := seen[strings.ToLower(name)]; ok {
+			continue
+		}
+		seen[strings.ToLower(name)] = struct{}{}
+		out = append(out, name)
+	}
+	for _, c := range selectIntoClauses(stripLeadingComments(query)) {
+		if _, ok := seen[strings.ToLower(c.target)]; ok || c.file {
+			continue
+		}
+		seen[strings.ToLower(c.target)] = struct{}{}
+		out = append(out, c.target)
+	}
+	return out
+}
+
+type selectIntoClause struct {
+	target     string
+	file       bool
+	start, end int
+}
+
+// selectIntoClauses finds Postgres SELECT ... INTO table and MySQL INTO
+// OUTFILE/DUMPFILE. INSERT/REPLACE/MERGE INTO and MySQL INTO @variable are not
+// included.
+func selectIntoClauses(query string) []selectIntoClause {
+	var out []selectIntoClause
+	for _, m := range selectIntoPattern.FindAllStringSubmatchIndex(maskStringLiterals(query), -1) {
+		if m[2] >= 0 {
+			switch strings.ToLower(strings.TrimSpace(query[m[2]:m[3]])) {
+			case "insert", "ignore", "replace", "merge":
+				continue
+			}
+		}
+		target := query[m[4]:m[5]]
+		if strings.HasPrefix(target, "@") {
+			continue
+		}
+		start := m[0]
+		if m[2] >= 0 {
+			start = m[3]
+		}
+		lower := strings.ToLower(target)
+		if lower == "outfile" || lower == "dumpfile" {
+			out = append(out, selectIntoClause{target: lower, file: true, start: start, end: m[5]})
+			continue
+		}
+		parts := strings.Split(target, ".")
+		for i := range parts {
+			parts[i] = unquoteIdent(parts[i])
+		}
+		out = append(out, selectIntoClause{target: strings.Join(parts, "."), start: start, end: m[5]})
+	}
+	return out
+}
+
+func tableAllowed(table string, allowed []string) bool {
+	_, bare, qualified := strings.Cut(table, ".")
+	if !qualified {
+		bare = table
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, table) || (!strings.Contains(a, ".") && strings.EqualFold(a, bare)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWriteStatement(query string) bool {
+	keyword := leadingKeyword(query)
+	if keyword == "" {
+		return false
+	}
+	_, ok := readStatementKeywords[keyword]
+	return !ok
+}
+
+func looksLikeSQL(query string) bool {
+	keyword := leadingKeyword(query)
+	if _, ok := readStatementKeywords[keyword]; ok {
+		return true
+	}
+	_, ok := writeStatementKeywords[keyword]
+	return ok
+}
+
+func returnsRows(query string) bool {
+	return returningPattern.MatchString(maskStringLiterals(stripLeadingComments(query)))
+}
+
+func leadingKeyword(query string) string {
+	cleaned := strings.TrimLeft(stripLeadingComments(query), "(")
+	fields := strings.Fields(cleaned)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimRight(fields[0], ";("))
+}
+
+func ensureLimit(query string, limit int, dialect string) string {
 	if limit <= 0 {
 		return query
 	}
@@ -35,15 +392,193 @@ func ensureLimit(query string, limit int) string {
 		return query
 	}
 
-	if hasLimitPattern.MatchString(lower) {
+	if hasLimitPattern.MatchString(lower) || hasFetchPattern.MatchString(lower) || hasTopPattern.MatchString(lower) {
+		return query
+	}
+
+	if isSingleRowQuery(cleaned) {
 		return query
 	}
 
 	trimmed := strings.TrimSpace(query)
 	trimmed = strings.TrimSuffix(trimmed, ";")
+	if dialect == dialectSQLServer {
+		return sqlServerLimit(trimmed, strings.TrimSuffix(cleaned, ";"), limit)
+	}
 	return fmt.Sprintf("%s LIMIT %d;", trimmed, limit)
 }
 
+func sqlServerLimit(trimmed, body string, limit int) string {
+	head := trimmed[:len(trimmed)-len(body)]
+	body = strings.TrimSpace(body)
+	lower := strings.ToLower(body)
+
+	compound := strings.HasPrefix(lower, "with") ||
+		indexTopLevelKeyword(body, "union") >= 0 ||
+		indexTopLevelKeyword(body, "intersect") >= 0 ||
+		indexTopLevelKeyword(body, "except") >= 0
+	ordered := indexTopLevelKeyword(body, "order") >= 0
+
+	if !ordered && !compound {
+		prefix := selectHeadPattern.FindString(body)
+		return fmt.Sprintf("%s%sTOP (%d) %s;", head, prefix, limit, body[len(prefix):])
+	}
+	// OFFSET/FETCH is only valid after an ORDER BY.
+	if !ordered {
+		body += " ORDER BY (SELECT NULL)"
+	}
+	return fmt.Sprintf("%s%s OFFSET 0 ROWS FETCH NEXT %d ROWS ONLY;", head, body, limit)
+}
+
+func effectiveLimit(query string) int {
+	q := strings.TrimSpace(query)
+	m := trailingLimitPattern.FindStringSubmatch(q)
+	if m == nil {
+		m = trailingFetchPattern.FindStringSubmatch(q)
+	}
+	if m == nil {
+		m = leadingTopPattern.FindStringSubmatch(stripLeadingComments(q))
+	}
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func cteMainKeyword(lower string) string {
+	depth := 0
+	var quote byte
+	for i := len("with"); i < len(lower); i++ {
+		c := lower[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth--
+		default:
+			if depth != 0 || !isIdentChar(c) || isIdentChar(lower[i-1]) {
+				continue
+			}
+			for _, kw := range []string{"select", "insert", "update", "delete", "merge", "values", "table"} {
+				end := i + len(kw)
+				if strings.HasPrefix(lower[i:], kw) && (end == len(lower) || !isIdentChar(lower[end])) {
+					return kw
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func isSingleRowQuery(query string) bool {
+	q := strings.TrimSuffix(strings.TrimSpace(stripLeadingComments(query)), ";")
+	lower := strings.ToLower(q)
+	if !strings.HasPrefix(lower, "select") || multiRowClausePattern.MatchString(lower) {
+		return false
+	}
+
+	body := strings.TrimSpace(q[len("select"):])
+	fromIdx := indexTopLevelKeyword(body, "from")
+	if fromIdx < 0 {
+		return true
+	}
+
+	items := splitTopLevel(body[:fromIdx], ',')
+	if len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		expr := strings.TrimSpace(item)
+		if !aggregateCallPattern.MatchString(expr) {
+			return false
+		}
+		expr = selectAliasPattern.ReplaceAllString(expr, "")
+		if !strings.HasSuffix(expr, ")") {
+			return false
+		}
+	}
+	return true
+}
+
+func indexTopLevelKeyword(s, keyword string) int {
+	lower := strings.ToLower(s)
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth--
+		default:
+			if depth == 0 && strings.HasPrefix(lower[i:], keyword) &&
+				(i == 0 || !isIdentChar(s[i-1])) &&
+				(i+len(keyword) == len(s) || !isIdentChar(s[i+len(keyword)])) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func splitTopLevel(s string, sep byte) []string {
+	parts := make([]string, 0)
+	depth := 0
+	var quote byte
+	last := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	if strings.TrimSpace(s[last:]) != "" {
+		parts = append(parts, s[last:])
+	}
+	return parts
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
 func stripLeadingComments(sqlText string) string {
 	s := strings.TrimSpace(sqlText)
 	for {
@@ -70,3 +605,87 @@ func stripLeadingComments(sqlText string) string {
 		return s
 	}
 }
+
+func referencedTables(query string) []string {
+	ctes := map[string]struct{}{}
+	for _, m := range cteNamePattern.FindAllStringSubmatch(query, -1) {
+		ctes[strings.ToLower(unquoteIdent(m[1]))] = struct{}{}
+	}
+
+	masked := maskStringLiterals(query)
+	seen := map[string]struct{}{}
+	out := make([]string, 0)
+	for _, idx := range tableReferencePattern.FindAllStringSubmatchIndex(masked, -1) {
+		ref := masked[idx[2]:idx[3]]
+		if strings.EqualFold(ref, "set") || insideFunctionCall(masked, idx[0]) {
+			continue
+		}
+		keyword := strings.ToLower(masked[idx[0] : idx[0]+4])
+		if (keyword == "from" || keyword == "join") && strings.HasPrefix(strings.TrimSpace(masked[idx[1]:]), "(") {
+			continue
+		}
+		// IS [NOT] DISTINCT FROM compares values; its operand is not a table.
+		if keyword == "from" && strings.HasSuffix(strings.ToLower(strings.TrimRight(masked[:idx[0]], " \t\n")), "distinct") {
+			continue
+		}
+		parts := strings.Split(ref, ".")
+		for i := range parts {
+			parts[i] = unquoteIdent(parts[i])
+		}
+		name := strings.Join(parts, ".")
+		key := strings.ToLower(name)
+		if _, ok := ctes[key]; ok {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, name)
+	}
+	return out
+}
+
+func maskStringLiterals(query string) string {
+	b := []byte(query)
+	inString := false
+	for i := 0; i < len(b); i++ {
+		if b[i] == '\'' {
+			inString = !inString
+			continue
+		}
+		if inString {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+func insideFunctionCall(query string, pos int) bool {
+	depth := 0
+	for i := pos - 1; i >= 0; i-- {
+		switch query[i] {
+		case ')':
+			depth++
+		case '(':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			inner := strings.ToLower(strings.TrimSpace(query[i+1:]))
+			if strings.HasPrefix(inner, "select") || strings.HasPrefix(inner, "with") {
+				return false
+			}
+			before := strings.TrimRight(query[:i], " \t\n")
+			return before != "" && isIdentChar(before[len(before)-1]) && !strings.HasSuffix(strings.ToLower(before), "from") && !strings.HasSuffix(strings.ToLower(before), "join")
+		}
+	}
+	return false
+}
+
+func unquoteIdent(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '`') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
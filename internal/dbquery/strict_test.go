@@ -0,0 +1,66 @@
+package dbquery
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnknownTableReferences(t *testing.T) {
+	known := []string{"public.users", "public.orders", "analytics.events"}
+	refs := []string{"users", "public.orders", "analytics.events", "customers", "public.events", "pg_catalog.pg_class", "information_schema.tables"}
+
+	got := unknownTableReferences(refs, known)
+	want := []string{"customers", "public.events"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unknownTableReferences = %#v, want %#v", got, want)
+	}
+}
+
+func TestCheckStrictSchemaSQLite(t *testing.T) {
+	db := openTestSQLite(t,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`,
+		`CREATE VIEW active_users AS SELECT * FROM users`,
+	)
+	ctx := context.Background()
+
+	ok := "WITH recent AS (SELECT * FROM users) SELECT r.email FROM recent r JOIN active_users a ON a.id = r.id"
+	if err := checkStrictSchema(ctx, db, "sqlite", ok); err != nil {
+		t.Fatalf("expected known tables, CTEs and aliases to pass, got %v", err)
+	}
+
+	if err := checkStrictSchema(ctx, db, "sqlite", "SELECT * FROM users WHERE email IS DISTINCT FROM id AND EXTRACT(YEAR FROM email) > 0"); err != nil {
+		t.Fatalf("expected DISTINCT FROM and EXTRACT operands not to count as tables, got %v", err)
+	}
+
+	err := checkStrictSchema(ctx, db, "sqlite", "SELECT * FROM users u JOIN customers c ON c.id = u.id")
+	if !errors.Is(err, ErrUnknownTable) || !strings.Contains(err.Error(), "customers") {
+		t.Fatalf("expected ErrUnknownTable naming customers, got %v", err)
+	}
+	if ExitCode(err) != ExitSafety {
+		t.Fatalf("expected safety exit code, got %d", ExitCode(err))
+	}
+}
+
+func TestClientQueryStrictSchema(t *testing.T) {
+	srv := newTestLLMServer(t, "SELECT * FROM customers")
+	cfg := DefaultConfig()
+	cfg.DBType = "sqlite"
+	cfg.DBURL = newTestSQLiteFile(t, `CREATE TABLE users (id INTEGER PRIMARY KEY)`)
+	cfg.APIKey = "test-key"
+	cfg.LLMBaseURL = srv.URL
+	cfg.StrictSchema = true
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, cfg)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	defer client.Close()
+
+	if _, _, err := client.Query(ctx, "all customers"); !errors.Is(err, ErrUnknownTable) {
+		t.Fatalf("expected ErrUnknownTable from Client.Query, got %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package dbquery
+
+import "os"
+
+func ttyRows(*os.File) int { return 0 }
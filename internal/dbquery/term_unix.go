@@ -0,0 +1,17 @@
+//go:build unix
+
+package dbquery
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func ttyRows(f *os.File) int {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0
+	}
+	return int(ws.Row)
+}